@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -21,6 +22,11 @@ func main() {
 	ctx := log.IntoContext(context.Background(), zap.New())
 	if err := prSizeCmd.ExecuteContext(ctx); err != nil {
 		logger.Error(err, "Could not process the command.")
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			exit(exitErr.Code)
+			return
+		}
 		exit(1)
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/cmd"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
@@ -31,6 +32,15 @@ func TestMain(t *testing.T) {
 			},
 			wantExitCode: 1,
 		},
+		{
+			name: "If the command returns with an exit error, main() exits with its exit code.",
+			prSizeCmd: &cobra.Command{
+				RunE: func(_ *cobra.Command, args []string) error {
+					return &cmd.ExitError{Code: cmd.ExitCodeSizeExceeded, Err: fmt.Errorf("too large")}
+				},
+			},
+			wantExitCode: 2,
+		},
 	}
 	for _, tt := range tcs {
 		t.Run(tt.name, func(t *testing.T) {
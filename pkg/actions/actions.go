@@ -0,0 +1,106 @@
+// Package actions implements interactions with a runner of GitHub Actions through files specified by environment
+// variables.
+package actions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdout is where workflow commands are written to.
+var stdout io.Writer = os.Stdout
+
+// Output is a step output of an action.
+type Output struct {
+	Name  string
+	Value string
+}
+
+// SetOutputs appends the outputs to a file at GITHUB_OUTPUT.  If the variable is not set (e.g. the command doesn't
+// run on GitHub Actions), the function does nothing.
+func SetOutputs(outputs ...Output) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, o := range outputs {
+		delimiter, err := newDelimiter()
+		if err != nil {
+			return err
+		}
+		if strings.Contains(o.Value, delimiter) {
+			return fmt.Errorf("output %q contains the delimiter %q", o.Name, delimiter)
+		}
+		// Use the multiline syntax so that values containing newlines don't break the file.
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", o.Name, delimiter, o.Value, delimiter)
+	}
+	return appendFile(path, b.String())
+}
+
+// AppendSummary appends a Markdown document to a job summary at GITHUB_STEP_SUMMARY.  If the variable is not set, the
+// function does nothing.
+func AppendSummary(markdown string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	if !strings.HasSuffix(markdown, "\n") {
+		markdown += "\n"
+	}
+	return appendFile(path, markdown)
+}
+
+// RunURL returns a URL of the current workflow run, or an empty string if the command doesn't run on GitHub Actions.
+func RunURL() string {
+	server, repo, id := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server == "" || repo == "" || id == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimSuffix(server, "/"), repo, id)
+}
+
+// Warning creates a warning annotation with the message.
+func Warning(message string) {
+	fmt.Fprintf(stdout, "::warning::%s\n", escapeData(message))
+}
+
+// Error creates an error annotation with the message.
+func Error(message string) {
+	fmt.Fprintf(stdout, "::error::%s\n", escapeData(message))
+}
+
+// escapeData escapes a message of a workflow command so that it doesn't break the command.
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+func newDelimiter() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate a delimiter: %w", err)
+	}
+	return "ghadelimiter_" + hex.EncodeToString(buf), nil
+}
+
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %q: %w", path, err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return fmt.Errorf("write to %q: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package actions_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, os.WriteFile(path, []byte("existing=value\n"), 0o644))
+	t.Setenv("GITHUB_OUTPUT", path)
+
+	err := actions.SetOutputs(
+		actions.Output{Name: "size", Value: "XL"},
+		actions.Output{Name: "files", Value: "a\nb"},
+	)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	re := regexp.MustCompile(`^existing=value
+size<<(ghadelimiter_[0-9a-f]+)
+XL
+(ghadelimiter_[0-9a-f]+)
+files<<(ghadelimiter_[0-9a-f]+)
+a
+b
+(ghadelimiter_[0-9a-f]+)
+$`)
+	m := re.FindStringSubmatch(string(data))
+	require.NotNil(t, m, string(data))
+	assert.Equal(t, m[1], m[2])
+	assert.Equal(t, m[3], m[4])
+}
+
+func TestSetOutputsWithoutGitHubOutput(t *testing.T) {
+	t.Setenv("GITHUB_OUTPUT", "")
+	assert.NoError(t, actions.SetOutputs(actions.Output{Name: "size", Value: "XL"}))
+}
+
+func TestSetOutputsReturnsError(t *testing.T) {
+	t.Setenv("GITHUB_OUTPUT", filepath.Join(t.TempDir(), "not-exist", "output"))
+	err := actions.SetOutputs(actions.Output{Name: "size", Value: "XL"})
+	assert.ErrorContains(t, err, "open ")
+}
+
+func TestAppendSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary")
+	t.Setenv("GITHUB_STEP_SUMMARY", path)
+
+	require.NoError(t, actions.AppendSummary("## First"))
+	require.NoError(t, actions.AppendSummary("## Second\n"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "## First\n## Second\n", string(data))
+
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+	assert.NoError(t, actions.AppendSummary("## Ignored"))
+}
+
+func TestAnnotations(t *testing.T) {
+	var buf bytes.Buffer
+	t.Cleanup(actions.SetStdout(&buf))
+
+	actions.Warning("100% too large\nsplit it")
+	actions.Error("failed")
+	assert.Equal(t, "::warning::100%25 too large%0Asplit it\n::error::failed\n", buf.String())
+}
+
+func TestRunURL(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "kkohtaka/gh-actions-pr-size")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	assert.Equal(t, "https://github.com/kkohtaka/gh-actions-pr-size/actions/runs/42", actions.RunURL())
+
+	t.Setenv("GITHUB_RUN_ID", "")
+	assert.Empty(t, actions.RunURL())
+}
@@ -0,0 +1,11 @@
+package actions
+
+import "io"
+
+func SetStdout(w io.Writer) func() {
+	orig := stdout
+	stdout = w
+	return func() {
+		stdout = orig
+	}
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/actions"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/config"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/report"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "backfill labels all open pull requests in a repository with their sizes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := bindInputs(cmd.Flags()); err != nil {
+			return err
+		}
+		return runBackfill(cmd.Context())
+	},
+}
+
+// defaultConcurrency is the default number of pull requests processed at the same time in a backfill.
+const defaultConcurrency = 4
+
+var concurrency int
+
+func init() {
+	backfillCmd.Flags().StringVar(
+		&repository,
+		"repo",
+		"",
+		"repository in the form of OWNER/REPO; if empty, GITHUB_REPOSITORY environment variable is used",
+	)
+	PRSizeCmd.PersistentFlags().IntVar(
+		&concurrency,
+		"concurrency",
+		defaultConcurrency,
+		"maximum number of pull requests processed at the same time in a backfill",
+	)
+	PRSizeCmd.AddCommand(backfillCmd)
+}
+
+func runBackfill(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	if concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: it must be positive", concurrency)
+	}
+	owner, repo, err := parseRepository()
+	if err != nil {
+		return err
+	}
+	client, _, err := newClient(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	// Read a configuration on the default branch since pull requests may target different branches.
+	cfg, err := loadConfig(ctx, client, owner, repo, "")
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+
+	prs, err := listOpenPullRequests(ctx, client, owner, repo)
+	if err != nil {
+		return fmt.Errorf("unable to list open pull requests: %w", err)
+	}
+	logger.Info("Listed open pull requests", "owner", owner, "repo", repo, "count", len(prs))
+
+	if !dryRun && cfg.Labels.Sync {
+		if _, err := gh.SyncLabels(ctx, client, owner, repo, sizer.Tiers()); err != nil {
+			return fmt.Errorf("unable to sync labels: %w", err)
+		}
+	}
+
+	relabels := make([]report.Relabel, len(prs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, pr := range prs {
+		wg.Add(1)
+		go func(i int, pr *github.PullRequest) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			relabels[i] = relabelPullRequest(ctx, client, cfg, sizer, owner, repo, pr)
+		}(i, pr)
+	}
+	wg.Wait()
+
+	if err := actions.AppendSummary(report.Backfill(relabels, dryRun)); err != nil {
+		return fmt.Errorf("unable to write a job summary: %w", err)
+	}
+
+	var errs []error
+	for _, r := range relabels {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("pull request #%d: %w", r.Number, r.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to relabel %d of %d pull requests: %w", len(errs), len(prs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// listOpenPullRequests returns all open pull requests in the repository.
+func listOpenPullRequests(ctx context.Context, client *github.Client, owner, repo string) ([]*github.PullRequest, error) {
+	var prs []*github.PullRequest
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		page, resp, err := client.PullRequests.List(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list pull requests: %w", err)
+		}
+		prs = append(prs, page...)
+		if resp.NextPage == 0 {
+			return prs, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// relabelPullRequest computes a size of the pull request and replaces its label of the tiers unless dry-run is enabled.
+func relabelPullRequest(
+	ctx context.Context,
+	client *github.Client,
+	cfg *config.Config,
+	sizer *gh.Sizer,
+	owner, repo string,
+	pr *github.PullRequest,
+) report.Relabel {
+	logger := log.FromContext(ctx).WithValues("owner", owner, "repo", repo, "number", pr.GetNumber())
+
+	r := report.Relabel{Number: pr.GetNumber(), Title: pr.GetTitle()}
+	known := make(map[string]struct{})
+	for _, label := range sizer.Labels() {
+		known[label] = struct{}{}
+	}
+	for _, label := range pr.Labels {
+		if _, ok := known[label.GetName()]; ok {
+			r.Before = append(r.Before, label.GetName())
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		r.Err = err
+		return r
+	}
+	m, err := measurePullRequest(ctx, client, cfg, sizer, owner, repo, pr, trustedAttributesRef(pr), false)
+	if err != nil {
+		logger.Error(err, "Failed to compute a size of a pull request")
+		r.Err = err
+		return r
+	}
+	r.After = m.size.GetLabel()
+
+	switch {
+	case !r.Changed():
+		logger.Info("The pull request already has the label", "label", r.After)
+	case dryRun:
+		logger.Info("Skipped relabeling the pull request since dry-run is enabled", "before", r.Before, "after", r.After)
+	default:
+		if err := gh.SetLabelOnPullRequest(ctx, client, owner, repo, pr.GetNumber(), sizer, m.size); err != nil {
+			logger.Error(err, "Failed to relabel a pull request")
+			r.Err = fmt.Errorf("unable to set a label on a pull request: %w", err)
+			return r
+		}
+		logger.Info("Relabeled the pull request", "before", r.Before, "after", r.After)
+	}
+	return r
+}
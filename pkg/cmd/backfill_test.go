@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfill(t *testing.T) {
+	// relabeled is a request to add labels to or remove a label from a pull request.
+	type relabeled struct {
+		number string
+		method string
+		labels []string
+	}
+
+	var setup = func(t *testing.T) (*[]relabeled, string) {
+		repository = ""
+		configFile = ""
+		dryRun = false
+		concurrency = defaultConcurrency
+		// A failure of a pull request is tested by a server error, which shouldn't be retried.
+		maxRetries = 0
+		t.Cleanup(func() {
+			maxRetries = gh.DefaultMaxRetries
+		})
+		t.Setenv("GITHUB_REPOSITORY", "kkohtaka/gh-actions-pr-size")
+		summary := filepath.Join(t.TempDir(), "summary.md")
+		t.Setenv("GITHUB_STEP_SUMMARY", summary)
+		PRSizeCmd.SetArgs([]string{"backfill"})
+		t.Cleanup(func() {
+			PRSizeCmd.SetArgs(nil)
+		})
+
+		httpmock.Activate()
+		t.Cleanup(httpmock.DeactivateAndReset)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls",
+			httpmock.NewJsonResponderOrPanic(200, []*github.PullRequest{
+				{
+					Number: github.Int(1),
+					Title:  github.String("Unchanged"),
+					Labels: []*github.Label{{Name: github.String("size/L")}},
+					Head:   &github.PullRequestBranch{SHA: github.String("abc")},
+				},
+				{
+					Number: github.Int(2),
+					Title:  github.String("Grown"),
+					Labels: []*github.Label{{Name: github.String("bug")}, {Name: github.String("size/S")}},
+					Head:   &github.PullRequestBranch{SHA: github.String("abc")},
+				},
+				{
+					Number: github.Int(3),
+					Title:  github.String("Broken"),
+					Head:   &github.PullRequestBranch{SHA: github.String("abc")},
+				},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/abc",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			`=~^https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/[12]/files`,
+			httpmock.NewJsonResponderOrPanic(200, []github.CommitFile{
+				{Additions: github.Int(100), Deletions: github.Int(200)},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/3/files",
+			httpmock.NewStringResponder(500, `{"message": "Server Error"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			`=~^https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/\d+/comments`,
+			httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{}),
+		)
+		// The labels are listed again after the stale one is removed.
+		listed := 0
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/2/labels",
+			func(req *http.Request) (*http.Response, error) {
+				listed++
+				size := "size/S"
+				if listed > 1 {
+					size = "size/L"
+				}
+				return httpmock.NewJsonResponse(200, []*github.Label{
+					{Name: github.String("bug")},
+					{Name: github.String(size)},
+				})
+			},
+		)
+		var (
+			mu  sync.Mutex
+			got []relabeled
+		)
+		httpmock.RegisterResponder(
+			"POST",
+			`=~^https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/(\d+)/labels`,
+			func(req *http.Request) (*http.Response, error) {
+				var labels []string
+				if err := json.NewDecoder(req.Body).Decode(&labels); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, relabeled{
+					number: httpmock.MustGetSubmatch(req, 1),
+					method: req.Method,
+					labels: labels,
+				})
+				return httpmock.NewJsonResponse(200, []*github.Label{
+					{Name: github.String("bug")},
+					{Name: github.String("size/S")},
+					{Name: github.String(labels[0])},
+				})
+			},
+		)
+		httpmock.RegisterResponder(
+			"DELETE",
+			`=~^https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/(\d+)/labels/(.+)`,
+			func(req *http.Request) (*http.Response, error) {
+				label, err := url.PathUnescape(httpmock.MustGetSubmatch(req, 2))
+				if err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, relabeled{
+					number: httpmock.MustGetSubmatch(req, 1),
+					method: req.Method,
+					labels: []string{label},
+				})
+				return httpmock.NewJsonResponse(200, []*github.Label{})
+			},
+		)
+		return &got, summary
+	}
+
+	t.Run("Open pull requests are relabeled.", func(t *testing.T) {
+		got, summary := setup(t)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, "unable to relabel 1 of 3 pull requests: pull request #3: ")
+		assert.Equal(t, []relabeled{
+			{number: "2", method: "POST", labels: []string{"size/L"}},
+			{number: "2", method: "DELETE", labels: []string{"size/S"}},
+		}, *got)
+
+		data, err := os.ReadFile(summary)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "Relabeled **1** of 3 open pull requests. 1 pull requests failed.\n")
+		assert.Contains(t, string(data), "| #2 Grown | `size/S` | `size/L` |\n")
+	})
+
+	t.Run("Dry-run is enabled.", func(t *testing.T) {
+		got, summary := setup(t)
+		dryRun = true
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+		assert.Empty(t, *got)
+
+		data, err := os.ReadFile(summary)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "Would relabel **1** of 3 open pull requests.")
+	})
+
+	t.Run("A schedule event triggers a backfill.", func(t *testing.T) {
+		got, _ := setup(t)
+		PRSizeCmd.SetArgs([]string{"--concurrency=1"})
+		t.Setenv("GITHUB_EVENT_NAME", "schedule")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, []relabeled{
+			{number: "2", method: "POST", labels: []string{"size/L"}},
+			{number: "2", method: "DELETE", labels: []string{"size/S"}},
+		}, *got)
+	})
+
+	t.Run("An invalid concurrency is specified.", func(t *testing.T) {
+		setup(t)
+		concurrency = 0
+		PRSizeCmd.SetArgs([]string{"backfill", "--concurrency=0"})
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, "invalid concurrency 0: it must be positive")
+	})
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"golang.org/x/oauth2"
+)
+
+// defaultAPIURL is a URL of GitHub API on github.com.
+const defaultAPIURL = "https://api.github.com"
+
+// newClient returns a client of GitHub API for the repository, and a login which the client comments as.  The client
+// is authenticated as an installation of a GitHub App if an ID of the app is specified by the flag or GITHUB_APP_ID, or
+// with the token otherwise.
+func newClient(ctx context.Context, owner, repo string) (*github.Client, *author, error) {
+	return newCachedClient(ctx, owner, repo, nil)
+}
+
+// newCachedClient returns a client of GitHub API for the repository like newClient, which caches responses in the
+// cache.  If the cache is nil, a new one is created for the client.
+func newCachedClient(ctx context.Context, owner, repo string, cache *gh.Cache) (*github.Client, *author, error) {
+	httpClient, appClient, err := newHTTPClient(ctx, owner, repo, cache)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to authenticate to GitHub API: %w", err)
+	}
+	client, err := newGitHubClient(httpClient, cache)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a := &author{
+		resolve: func(ctx context.Context) (string, error) {
+			return gh.AuthenticatedLogin(ctx, client)
+		},
+	}
+	if appClient != nil {
+		a.resolve = func(ctx context.Context) (string, error) {
+			return gh.AppBotLogin(ctx, appClient)
+		}
+	}
+	return client, a, nil
+}
+
+// author is a login which a client comments as.  It's resolved on the first comment, since it costs a request which
+// runs without comments don't need.
+type author struct {
+	mu      sync.Mutex
+	name    string
+	resolve func(ctx context.Context) (string, error)
+}
+
+// login returns the login, which is resolved on the first call.
+func (a *author) login(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.name == "" {
+		name, err := a.resolve(ctx)
+		if err != nil {
+			return "", err
+		}
+		a.name = name
+	}
+	return a.name, nil
+}
+
+// newHTTPClient returns an HTTP client which authenticates requests to GitHub API for the repository.  If it
+// authenticates as an installation of a GitHub App, a client authenticated as the app, which caches responses in the
+// cache, is also returned.
+func newHTTPClient(ctx context.Context, owner, repo string, cache *gh.Cache) (*http.Client, *github.Client, error) {
+	id := appID
+	if id == 0 {
+		if v := os.Getenv("GITHUB_APP_ID"); v != "" {
+			var err error
+			if id, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, nil, fmt.Errorf("invalid GITHUB_APP_ID %q: %w", v, err)
+			}
+		}
+	}
+	if id != 0 {
+		key := appPrivateKey
+		if key == "" {
+			key = os.Getenv("GITHUB_APP_PRIVATE_KEY")
+		}
+		if key == "" {
+			return nil, nil, errors.New("a private key of a GitHub App must be specified by --app-private-key or " +
+				"GITHUB_APP_PRIVATE_KEY")
+		}
+		jwt, err := gh.NewAppJWTSource(id, []byte(key))
+		if err != nil {
+			return nil, nil, err
+		}
+		appClient, err := newGitHubClient(oauth2.NewClient(ctx, jwt), cache)
+		if err != nil {
+			return nil, nil, err
+		}
+		return oauth2.NewClient(ctx, gh.NewInstallationTokenSource(ctx, appClient, owner, repo)), appClient, nil
+	}
+
+	accessToken := token
+	if accessToken == "" {
+		accessToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if accessToken == "" {
+		return nil, nil, nil
+	}
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: accessToken},
+	)
+	return oauth2.NewClient(ctx, ts), nil, nil
+}
+
+// newGitHubClient returns a client of GitHub API which sends requests by the HTTP client.  If a URL of GitHub API other
+// than github.com is specified by the flag or GITHUB_API_URL, which GitHub Actions sets on GitHub Enterprise Server,
+// the client accesses the URL instead.
+//
+// Responses are cached in the cache to send conditional requests, or in memory and cacheDir if the cache is nil, and
+// requests failed by rate limits or server errors are retried at most maxRetries times, waiting at most maxWait each
+// time.
+func newGitHubClient(httpClient *http.Client, cache *gh.Cache) (*github.Client, error) {
+	var base http.RoundTripper
+	if httpClient != nil {
+		base = httpClient.Transport
+	}
+	if cache == nil {
+		var err error
+		if cache, err = gh.NewCache(cacheDir); err != nil {
+			return nil, fmt.Errorf("unable to cache responses of GitHub API: %w", err)
+		}
+	}
+	retry := gh.NewRetryTransport(cache.Transport(base), maxRetries)
+	retry.MaxWait = maxWait
+	httpClient = &http.Client{Transport: retry}
+
+	u := apiURL
+	if u == "" {
+		u = os.Getenv("GITHUB_API_URL")
+	}
+	if u == "" || strings.TrimSuffix(u, "/") == defaultAPIURL {
+		return github.NewClient(httpClient), nil
+	}
+	client, err := newEnterpriseClient(u, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a client of GitHub API: %w", err)
+	}
+	return client, nil
+}
+
+// newEnterpriseClient returns a client of GitHub Enterprise Server whose REST API is served at apiURL (e.g.
+// "https://github.example.com/api/v3").  The suffix "/api/v3" is appended to apiURL if it doesn't have one.
+func newEnterpriseClient(apiURL string, httpClient *http.Client) (*github.Client, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %w", apiURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid API URL %q: an absolute URL is required", apiURL)
+	}
+	client, err := github.NewEnterpriseClient(apiURL, apiURL, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %w", apiURL, err)
+	}
+	// Assets are uploaded to "/api/uploads" instead of "/api/v3" on GitHub Enterprise Server, which go-github of this
+	// version doesn't take into account.
+	upload := *client.BaseURL
+	upload.Path = strings.TrimSuffix(upload.Path, "api/v3/") + "api/uploads/"
+	client.UploadURL = &upload
+	return client, nil
+}
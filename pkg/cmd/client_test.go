@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	tcs := []struct {
+		name          string
+		flag          string
+		env           string
+		wantBaseURL   string
+		wantUploadURL string
+	}{
+		{
+			name:          "No URL is specified.",
+			wantBaseURL:   "https://api.github.com/",
+			wantUploadURL: "https://uploads.github.com/",
+		},
+		{
+			name:          "The URL of github.com is specified.",
+			env:           "https://api.github.com",
+			wantBaseURL:   "https://api.github.com/",
+			wantUploadURL: "https://uploads.github.com/",
+		},
+		{
+			name:          "A URL of GitHub Enterprise Server is specified by GITHUB_API_URL.",
+			env:           "https://github.example.com/api/v3",
+			wantBaseURL:   "https://github.example.com/api/v3/",
+			wantUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:          "A URL without the path of REST API is specified.",
+			env:           "https://github.example.com",
+			wantBaseURL:   "https://github.example.com/api/v3/",
+			wantUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:          "The flag takes precedence over GITHUB_API_URL.",
+			flag:          "https://github.example.com/api/v3/",
+			env:           "https://api.github.com",
+			wantBaseURL:   "https://github.example.com/api/v3/",
+			wantUploadURL: "https://github.example.com/api/uploads/",
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			apiURL = tt.flag
+			t.Cleanup(func() {
+				apiURL = ""
+			})
+			t.Setenv("GITHUB_API_URL", tt.env)
+
+			client, _, err := newClient(context.Background(), "kkohtaka", "gh-actions-pr-size")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBaseURL, client.BaseURL.String())
+			assert.Equal(t, tt.wantUploadURL, client.UploadURL.String())
+		})
+	}
+}
+
+func TestNewClientReturnsError(t *testing.T) {
+	tcs := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "A relative URL of GitHub API is specified.",
+			env:     map[string]string{"GITHUB_API_URL": "github.example.com/api/v3"},
+			wantErr: `invalid API URL "github.example.com/api/v3": an absolute URL is required`,
+		},
+		{
+			name:    "An ID of a GitHub App is not a number.",
+			env:     map[string]string{"GITHUB_APP_ID": "app"},
+			wantErr: `invalid GITHUB_APP_ID "app": `,
+		},
+		{
+			name:    "A private key of a GitHub App is not specified.",
+			env:     map[string]string{"GITHUB_APP_ID": "42", "GITHUB_APP_PRIVATE_KEY": ""},
+			wantErr: "a private key of a GitHub App must be specified by --app-private-key or GITHUB_APP_PRIVATE_KEY",
+		},
+		{
+			name:    "A private key of a GitHub App is malformed.",
+			env:     map[string]string{"GITHUB_APP_ID": "42", "GITHUB_APP_PRIVATE_KEY": "key"},
+			wantErr: "parse a private key: no PEM block is found",
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, _, err := newClient(context.Background(), "kkohtaka", "gh-actions-pr-size")
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestEnterpriseServer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	tcs := []struct {
+		name              string
+		env               map[string]string
+		wantAuthorization string
+	}{
+		{
+			name:              "A token is specified.",
+			env:               map[string]string{"GITHUB_TOKEN": "secret"},
+			wantAuthorization: "Bearer secret",
+		},
+		{
+			name: "A GitHub App is specified.",
+			env: map[string]string{
+				"GITHUB_TOKEN":           "ignored",
+				"GITHUB_APP_ID":          "42",
+				"GITHUB_APP_PRIVATE_KEY": string(privateKey),
+			},
+			wantAuthorization: "token installation-token",
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuthorization string
+			var created []string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v3/repos/kkohtaka/gh-actions-pr-size/installation",
+				func(w http.ResponseWriter, r *http.Request) {
+					if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+						http.Error(w, `{"message": "A JSON web token is required"}`, http.StatusUnauthorized)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&github.Installation{ID: github.Int64(7)})
+				},
+			)
+			mux.HandleFunc("/api/v3/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+				expiresAt := time.Now().Add(time.Hour)
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(&github.InstallationToken{
+					Token:     github.String("installation-token"),
+					ExpiresAt: &expiresAt,
+				})
+			})
+			mux.HandleFunc("/api/v3/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+				func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+				},
+			)
+			mux.HandleFunc("/api/v3/repos/kkohtaka/gh-actions-pr-size/labels",
+				func(w http.ResponseWriter, r *http.Request) {
+					gotAuthorization = r.Header.Get("Authorization")
+					if r.Method == http.MethodGet {
+						_ = json.NewEncoder(w).Encode([]*github.Label{})
+						return
+					}
+					var label github.Label
+					if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					created = append(created, label.GetName())
+					w.WriteHeader(http.StatusCreated)
+					_ = json.NewEncoder(w).Encode(label)
+				},
+			)
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			repository = ""
+			configFile = ""
+			dryRun = false
+			token = ""
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			t.Setenv("GITHUB_API_URL", server.URL+"/api/v3")
+			t.Setenv("GITHUB_REPOSITORY", "kkohtaka/gh-actions-pr-size")
+			PRSizeCmd.SetArgs([]string{"labels", "sync"})
+			t.Cleanup(func() {
+				PRSizeCmd.SetArgs(nil)
+			})
+
+			err := PRSizeCmd.ExecuteContext(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAuthorization, gotAuthorization)
+			assert.Equal(t, []string{"size/XS", "size/S", "size/M", "size/L", "size/XL", "size/XXL"}, created)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/actions"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// reactionAccepted is a reaction to a comment with a command which was carried out.
+	reactionAccepted = "+1"
+	// reactionRejected is a reaction to a comment with a command which was malformed or not permitted.
+	reactionRejected = "confused"
+)
+
+// runIssueComment carries out a slash command in a comment on a pull request, e.g. "/size recompute" or "/size
+// override L".  Comments without commands are ignored.
+func runIssueComment(ctx context.Context, payload []byte) error {
+	logger := log.FromContext(ctx)
+
+	var event github.IssueCommentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("unable to unmarshal an event payload to JSON: %w", err)
+	}
+	if event.GetAction() != "created" || !event.GetIssue().IsPullRequest() {
+		logger.Info("Ignored a comment which wasn't created on a pull request", "action", event.GetAction())
+		return nil
+	}
+	command, parseErr := gh.ParseCommand(event.GetComment().GetBody())
+	if command == nil && parseErr == nil {
+		logger.Info("Ignored a comment without commands")
+		return nil
+	}
+
+	owner := event.GetRepo().GetOwner().GetLogin()
+	repo := event.GetRepo().GetName()
+	number := event.GetIssue().GetNumber()
+	user := event.GetComment().GetUser().GetLogin()
+	logger = logger.WithValues("owner", owner, "repo", repo, "number", number, "user", user)
+	ctx = log.IntoContext(ctx, logger)
+	logger.Info("Successfully read an event payload")
+
+	client, author, err := newClient(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	reject := func(message string) error {
+		actions.Warning(message)
+		return react(ctx, client, owner, repo, event.GetComment(), reactionRejected)
+	}
+
+	if parseErr != nil {
+		return reject(parseErr.Error())
+	}
+	ok, err := gh.CanPush(ctx, client, owner, repo, user)
+	if err != nil {
+		return fmt.Errorf("unable to verify a permission of a commenter: %w", err)
+	}
+	if !ok {
+		return reject(fmt.Sprintf("@%s doesn't have write permission, so the command is ignored.", user))
+	}
+
+	pr, _, err := client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return fmt.Errorf("unable to get a pull request: %w", err)
+	}
+	cfg, err := loadConfig(ctx, client, owner, repo, pr.GetBase().GetRef())
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	if command.Verb == gh.VerbOverride && sizer.Index(command.Tier) < 0 {
+		return reject(fmt.Sprintf("%q is not a tier, so the command is ignored.", command.Tier))
+	}
+	logger.Info("Carrying out a command", "verb", command.Verb, "tier", command.Tier)
+
+	// The command itself is read again as the latest one by the pipeline.
+	err = runPullRequest(ctx, client, author, cfg, owner, repo, pr, trustedAttributesRef(pr))
+	var exitErr *ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		if reactErr := react(ctx, client, owner, repo, event.GetComment(), reactionRejected); reactErr != nil {
+			logger.Error(reactErr, "Failed to react to a comment")
+		}
+		return err
+	}
+	if reactErr := react(ctx, client, owner, repo, event.GetComment(), reactionAccepted); reactErr != nil {
+		return reactErr
+	}
+	return err
+}
+
+// react adds a reaction to the comment to acknowledge a command unless dry-run is enabled.
+func react(ctx context.Context, client *github.Client, owner, repo string, comment *github.IssueComment, content string) error {
+	logger := log.FromContext(ctx).WithValues("comment", comment.GetID(), "reaction", content)
+	if dryRun {
+		logger.Info("Skipped reacting to a comment since dry-run is enabled")
+		return nil
+	}
+	if _, _, err := client.Reactions.CreateIssueCommentReaction(ctx, owner, repo, comment.GetID(), content); err != nil {
+		return fmt.Errorf("unable to react to a comment: %w", err)
+	}
+	logger.Info("Reacted to a comment")
+	return nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueComment(t *testing.T) {
+	var (
+		gotCreatedLabels []string
+		gotReactions     []string
+	)
+	var setup = func(t *testing.T, user, body string) {
+		t.Setenv("GITHUB_EVENT_NAME", "issue_comment")
+		comment := &github.IssueComment{
+			ID:   github.Int64(7),
+			Body: github.String(body),
+			User: &github.User{Login: github.String(user)},
+		}
+		writePayload(t, &github.IssueCommentEvent{
+			Action: github.String("created"),
+			Issue: &github.Issue{
+				Number:           github.Int(42),
+				PullRequestLinks: &github.PullRequestLinks{URL: github.String("https://example.com/pulls/42")},
+			},
+			Comment: comment,
+			Repo: &github.Repository{
+				Owner: &github.User{Login: github.String("kkohtaka")},
+				Name:  github.String("gh-actions-pr-size"),
+			},
+		})
+
+		configFile = ""
+		dryRun = false
+		httpmock.Activate()
+		t.Cleanup(httpmock.DeactivateAndReset)
+
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/maintainer/permission",
+			httpmock.NewJsonResponderOrPanic(200, &github.RepositoryPermissionLevel{Permission: github.String("admin")}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/contributor/permission",
+			httpmock.NewJsonResponderOrPanic(200, &github.RepositoryPermissionLevel{Permission: github.String("read")}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42",
+			httpmock.NewJsonResponderOrPanic(200, &github.PullRequest{
+				Number: github.Int(42),
+				Base: &github.PullRequestBranch{
+					Ref:  github.String("master"),
+					SHA:  github.String("def"),
+					Repo: &github.Repository{ID: github.Int64(1)},
+				},
+				// The pull request is from a fork, whose head isn't trusted.
+				Head: &github.PullRequestBranch{
+					Ref:  github.String("feature"),
+					SHA:  github.String("abc"),
+					Repo: &github.Repository{ID: github.Int64(2)},
+				},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/def",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewJsonResponderOrPanic(200, []github.CommitFile{
+				{Additions: github.Int(100), Deletions: github.Int(200)},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{comment}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+			httpmock.NewJsonResponderOrPanic(200, []*github.Label{}),
+		)
+		gotCreatedLabels = nil
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+			func(req *http.Request) (*http.Response, error) {
+				var labels []string
+				if err := json.NewDecoder(req.Body).Decode(&labels); err != nil {
+					return nil, err
+				}
+				gotCreatedLabels = append(gotCreatedLabels, labels...)
+				return httpmock.NewJsonResponse(200, []*github.Label{})
+			},
+		)
+		gotReactions = nil
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/comments/7/reactions",
+			func(req *http.Request) (*http.Response, error) {
+				var reaction github.Reaction
+				if err := json.NewDecoder(req.Body).Decode(&reaction); err != nil {
+					return nil, err
+				}
+				gotReactions = append(gotReactions, reaction.GetContent())
+				return httpmock.NewJsonResponse(201, reaction)
+			},
+		)
+	}
+
+	t.Run("The size is overridden by a collaborator.", func(t *testing.T) {
+		setup(t, "maintainer", "/size override S")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/S"}, gotCreatedLabels)
+		assert.Equal(t, []string{"+1"}, gotReactions)
+	})
+
+	t.Run("The size is recomputed by a collaborator.", func(t *testing.T) {
+		setup(t, "maintainer", "/size recompute")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
+		assert.Equal(t, []string{"+1"}, gotReactions)
+	})
+
+	t.Run("A command by a user without write permission is rejected.", func(t *testing.T) {
+		setup(t, "contributor", "/size override XS")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, gotCreatedLabels)
+		assert.Equal(t, []string{"confused"}, gotReactions)
+	})
+
+	t.Run("An override to an unknown tier is rejected.", func(t *testing.T) {
+		setup(t, "maintainer", "/size override XXXL")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, gotCreatedLabels)
+		assert.Equal(t, []string{"confused"}, gotReactions)
+	})
+
+	t.Run("A malformed command is rejected.", func(t *testing.T) {
+		setup(t, "maintainer", "/size please")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, gotCreatedLabels)
+		assert.Equal(t, []string{"confused"}, gotReactions)
+	})
+
+	t.Run("A comment without commands is ignored.", func(t *testing.T) {
+		setup(t, "maintainer", "LGTM")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, gotCreatedLabels)
+		assert.Empty(t, gotReactions)
+	})
+
+	t.Run("A comment on an issue is ignored.", func(t *testing.T) {
+		setup(t, "maintainer", "/size recompute")
+		writePayload(t, &github.IssueCommentEvent{
+			Action:  github.String("created"),
+			Issue:   &github.Issue{Number: github.Int(42)},
+			Comment: &github.IssueComment{ID: github.Int64(7), Body: github.String("/size recompute")},
+		})
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, gotCreatedLabels)
+		assert.Empty(t, gotReactions)
+	})
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// inputEnvPrefix is a prefix of environment variables by which GitHub Actions passes inputs to an action.
+const inputEnvPrefix = "INPUT_"
+
+// lookupInput returns a value of an action input with the name.  GitHub Actions upper-cases the name and keeps
+// hyphens in it, but a variant with underscores is also accepted since some shells can't set such variables.  A
+// blank value is treated as unspecified.
+func lookupInput(name string) (string, bool) {
+	name = strings.ToUpper(name)
+	for _, key := range []string{
+		inputEnvPrefix + name,
+		inputEnvPrefix + strings.ReplaceAll(name, "-", "_"),
+	} {
+		if v := os.Getenv(key); strings.TrimSpace(v) != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// splitInputList splits a value of an action input which is a list separated by newlines or commas.
+func splitInputList(v string) []string {
+	var res []string
+	for _, line := range strings.Split(v, "\n") {
+		for _, item := range strings.Split(line, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
+
+// bindInputs sets flags from action inputs.  Flags specified on the command line take precedence over inputs, and
+// inputs take precedence over default values of flags.
+func bindInputs(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		v, ok := lookupInput(f.Name)
+		if !ok {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			if e := sv.Replace(splitInputList(v)); e != nil {
+				err = fmt.Errorf("invalid input %q: %w", f.Name, e)
+			}
+			return
+		}
+		if e := f.Value.Set(v); e != nil {
+			err = fmt.Errorf("invalid input %q: %w", f.Name, e)
+		}
+	})
+	return err
+}
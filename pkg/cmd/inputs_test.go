@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindInputs(t *testing.T) {
+	var (
+		name     string
+		patterns []string
+		bounds   []int
+		enabled  bool
+		other    string
+	)
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.StringVar(&name, "label-prefix", "default", "")
+		flags.StringSliceVar(&patterns, "exclude", nil, "")
+		flags.IntSliceVar(&bounds, "thresholds", nil, "")
+		flags.BoolVar(&enabled, "dry-run", false, "")
+		flags.StringVar(&other, "other", "default", "")
+		return flags
+	}
+
+	t.Run("Inputs override default values.", func(t *testing.T) {
+		t.Setenv("INPUT_LABEL-PREFIX", "pr-size: ")
+		t.Setenv("INPUT_EXCLUDE", "**/go.sum\n**/package-lock.json, **/*.snap\n")
+		t.Setenv("INPUT_THRESHOLDS", "10,100")
+		t.Setenv("INPUT_DRY_RUN", "true")
+		t.Setenv("INPUT_OTHER", "")
+
+		require.NoError(t, bindInputs(newFlags()))
+		assert.Equal(t, "pr-size: ", name)
+		assert.Equal(t, []string{"**/go.sum", "**/package-lock.json", "**/*.snap"}, patterns)
+		assert.Equal(t, []int{10, 100}, bounds)
+		assert.True(t, enabled)
+		assert.Equal(t, "default", other)
+	})
+
+	t.Run("Flags on the command line take precedence over inputs.", func(t *testing.T) {
+		t.Setenv("INPUT_LABEL-PREFIX", "pr-size: ")
+		t.Setenv("INPUT_EXCLUDE", "**/go.sum")
+
+		flags := newFlags()
+		require.NoError(t, flags.Parse([]string{"--label-prefix=size/", "--exclude=*.md"}))
+		require.NoError(t, bindInputs(flags))
+		assert.Equal(t, "size/", name)
+		assert.Equal(t, []string{"*.md"}, patterns)
+	})
+
+	t.Run("An input has an invalid value.", func(t *testing.T) {
+		t.Setenv("INPUT_DRY-RUN", "maybe")
+
+		err := bindInputs(newFlags())
+		assert.ErrorContains(t, err, `invalid input "dry-run": `)
+	})
+}
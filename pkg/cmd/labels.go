@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var labelsCmd = &cobra.Command{
+	Use:   "labels",
+	Short: "labels manages labels of tiers in a repository",
+}
+
+var labelsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "sync creates labels of tiers in a repository and updates their colors and descriptions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := bindInputs(cmd.Flags()); err != nil {
+			return err
+		}
+		return runLabelsSync(cmd.Context())
+	},
+}
+
+var repository string
+
+func init() {
+	labelsSyncCmd.Flags().StringVar(
+		&repository,
+		"repo",
+		"",
+		"repository in the form of OWNER/REPO; if empty, GITHUB_REPOSITORY environment variable is used",
+	)
+	labelsCmd.AddCommand(labelsSyncCmd)
+	PRSizeCmd.AddCommand(labelsCmd)
+}
+
+// parseRepository returns an owner and a name of the repository specified by the flag or GITHUB_REPOSITORY.
+func parseRepository() (string, string, error) {
+	r := repository
+	if r == "" {
+		r = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if r == "" {
+		return "", "", fmt.Errorf("a repository must be specified by --repo or GITHUB_REPOSITORY")
+	}
+	owner, name, ok := strings.Cut(r, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid repository %q: OWNER/REPO is required", r)
+	}
+	return owner, name, nil
+}
+
+func runLabelsSync(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	owner, repo, err := parseRepository()
+	if err != nil {
+		return err
+	}
+	client, _, err := newClient(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	// Read a configuration on the default branch since labels don't belong to any pull request.
+	cfg, err := loadConfig(ctx, client, owner, repo, "")
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+
+	if dryRun {
+		for _, tier := range sizer.Tiers() {
+			logger.Info("Skipped syncing a label since dry-run is enabled",
+				"label", tier.Label,
+				"color", tier.Color,
+				"description", tier.Description,
+			)
+		}
+		return nil
+	}
+
+	changes, err := gh.SyncLabels(ctx, client, owner, repo, sizer.Tiers())
+	if err != nil {
+		return fmt.Errorf("unable to sync labels: %w", err)
+	}
+	for _, change := range changes {
+		logger.Info("Synced a label", "label", change.Label, "action", change.Action)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelsSync(t *testing.T) {
+	var setup = func(t *testing.T) *[]string {
+		repository = ""
+		configFile = ""
+		dryRun = false
+		t.Setenv("GITHUB_REPOSITORY", "kkohtaka/gh-actions-pr-size")
+		PRSizeCmd.SetArgs([]string{"labels", "sync"})
+		t.Cleanup(func() {
+			PRSizeCmd.SetArgs(nil)
+		})
+
+		httpmock.Activate()
+		t.Cleanup(httpmock.DeactivateAndReset)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/labels",
+			httpmock.NewJsonResponderOrPanic(200, []*github.Label{
+				{Name: github.String("size/XS"), Color: github.String("009900"), Description: github.String("0-9 changed lines")},
+			}),
+		)
+		var created []string
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/labels",
+			func(req *http.Request) (*http.Response, error) {
+				var label github.Label
+				if err := json.NewDecoder(req.Body).Decode(&label); err != nil {
+					return nil, err
+				}
+				created = append(created, label.GetName())
+				return httpmock.NewJsonResponse(201, label)
+			},
+		)
+		return &created
+	}
+
+	t.Run("Labels of the default tiers are created.", func(t *testing.T) {
+		created := setup(t)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/S", "size/M", "size/L", "size/XL", "size/XXL"}, *created)
+	})
+
+	t.Run("Dry-run is enabled.", func(t *testing.T) {
+		created := setup(t)
+		t.Setenv("INPUT_DRY-RUN", "true")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, *created)
+	})
+
+	t.Run("An invalid repository is specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("GITHUB_REPOSITORY", "gh-actions-pr-size")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, `invalid repository "gh-actions-pr-size": OWNER/REPO is required`)
+	})
+
+	t.Run("No repository is specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("GITHUB_REPOSITORY", "")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, "a repository must be specified by --repo or GITHUB_REPOSITORY")
+	})
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/git"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/policy"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/report"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var localCmd = &cobra.Command{
+	Use:   "local",
+	Short: "local computes a size of changes between two refs in a local repository without GitHub API",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLocal(cmd.Context(), cmd.OutOrStdout())
+	},
+}
+
+var (
+	baseRef string
+	headRef string
+	repoDir string
+)
+
+func init() {
+	localCmd.Flags().StringVar(
+		&baseRef,
+		"base",
+		"",
+		"ref of a base branch; changes since the merge base of the base and the head are counted",
+	)
+	localCmd.Flags().StringVar(&headRef, "head", "HEAD", "ref of a head branch")
+	localCmd.Flags().StringVar(&repoDir, "dir", ".", "directory of a local repository")
+	localCmd.Flags().StringSliceVar(
+		&includePatterns,
+		"include",
+		nil,
+		"glob patterns of files counted in the size, in addition to ones in the configuration",
+	)
+	localCmd.Flags().StringSliceVar(
+		&excludePatterns,
+		"exclude",
+		nil,
+		"glob patterns of files not counted in the size, in addition to ones in the configuration",
+	)
+	localCmd.Flags().StringVar(
+		&maxSize,
+		"max-size",
+		"",
+		"name of the largest acceptable tier; the command fails for larger changes",
+	)
+	cobra.CheckErr(localCmd.MarkFlagRequired("base"))
+	PRSizeCmd.AddCommand(localCmd)
+}
+
+// runLocal computes a size of changes between the merge base of the base and the head in a local repository, and
+// writes a report of it.  The configuration is read from the merge base, and attributes of files from the head.
+func runLocal(ctx context.Context, out io.Writer) error {
+	logger := log.FromContext(ctx)
+
+	repo := git.NewRepository(repoDir)
+	mergeBase, err := repo.MergeBase(ctx, baseRef, headRef)
+	if err != nil {
+		return fmt.Errorf("unable to find a merge base of %q and %q: %w", baseRef, headRef, err)
+	}
+	logger.Info("Found a merge base", "base", baseRef, "head", headRef, "mergeBase", mergeBase)
+
+	cfg, err := loadConfigFromTree(ctx, repo.Tree(mergeBase), mergeBase)
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	globFilter, err := cfg.GlobFilter()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+
+	files, err := repo.Diff(ctx, mergeBase, headRef)
+	if err != nil {
+		return fmt.Errorf("unable to compute a diff: %w", err)
+	}
+	changed, err := gh.CountChangedLines(ctx, files, globFilter, gh.NewTreeLinguistFilter(repo.Tree(headRef)))
+	if err != nil {
+		return fmt.Errorf("unable to count changed lines: %w", err)
+	}
+
+	size := sizer.Size(changed.Total())
+	logger.Info("Got a size of changes",
+		"size", size.String(),
+		"changed", changed.Total(),
+		"excluded", changed.ExcludedLines(),
+	)
+
+	// There's no pull request, so neither a bypass label nor a bypass keyword exempts the changes.
+	verdict := cfg.Policy.Evaluate(sizer, size, &github.PullRequest{})
+	r := &report.Report{Size: size, Tiers: sizer.Tiers(), Changes: changed}
+	if _, err := fmt.Fprintf(out, "%s\n%s\n", r.Markdown(), verdict.Message); err != nil {
+		return fmt.Errorf("unable to write a report: %w", err)
+	}
+
+	if verdict.Result == policy.Fail {
+		return &ExitError{Code: ExitCodeSizeExceeded, Err: errors.New(verdict.Message)}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	var setup = func(t *testing.T, args ...string) *bytes.Buffer {
+		dir := t.TempDir()
+		run := func(args ...string) {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+		write := func(name, content string) {
+			p := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+		}
+
+		run("init", "-q", "-b", "main")
+		write(".github/pr-size.yaml", "exclude:\n- \"**/go.sum\"\n")
+		run("add", "-A")
+		run("commit", "-q", "-m", "base")
+		run("checkout", "-q", "-b", "feature")
+		write("main.go", strings.Repeat("// line\n", 40))
+		write("go.sum", strings.Repeat("sum\n", 1000))
+		run("add", "-A")
+		run("commit", "-q", "-m", "feature")
+
+		configFile = ""
+		maxSize = ""
+		includePatterns = nil
+		excludePatterns = nil
+		var out bytes.Buffer
+		PRSizeCmd.SetOut(&out)
+		PRSizeCmd.SetArgs(append([]string{"local", "--dir", dir, "--base", "main", "--head", "feature"}, args...))
+		t.Cleanup(func() {
+			PRSizeCmd.SetOut(nil)
+			PRSizeCmd.SetArgs(nil)
+			maxSize = ""
+		})
+		return &out
+	}
+
+	t.Run("A size of changes between two refs is reported.", func(t *testing.T) {
+		out := setup(t)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "## Pull request size: M\n")
+		assert.Contains(t, out.String(), "This pull request changes **40** lines (+40 / -0) in 1 files and is labeled `size/M`.\n")
+		assert.Contains(t, out.String(), "1000 lines in 1 excluded files are not counted.\n")
+	})
+
+	t.Run("The changes exceed the maximum size.", func(t *testing.T) {
+		setup(t, "--max-size", "S")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		var exitErr *ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, ExitCodeSizeExceeded, exitErr.Code)
+	})
+
+	t.Run("An unknown base is specified.", func(t *testing.T) {
+		setup(t, "--base", "unknown")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, `unable to find a merge base of "unknown" and "feature": `)
+	})
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/actions"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/policy"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// mergeGroupEvent is a payload of a merge_group event, which go-github doesn't support yet.
+type mergeGroupEvent struct {
+	// Action is an action performed on the merge group.  Only "checks_requested" is defined.
+	Action     string             `json:"action"`
+	MergeGroup mergeGroup         `json:"merge_group"`
+	Repo       *github.Repository `json:"repository"`
+}
+
+// mergeGroup is a group of pull requests in a merge queue which are tested together.
+type mergeGroup struct {
+	// HeadSHA is a SHA of the commit which merges the pull requests into the base branch.
+	HeadSHA string `json:"head_sha"`
+	// HeadRef is a temporary branch of the merge group (e.g. "refs/heads/gh-readonly-queue/main/pr-42-<sha>").
+	HeadRef string `json:"head_ref"`
+	// BaseRef is a branch which the pull requests are merged into (e.g. "refs/heads/main").
+	BaseRef string `json:"base_ref"`
+}
+
+// mergeGroupRefPattern matches a head ref of a merge group, whose last pull request is the one added to the queue.
+// Pull requests ahead of it in the queue have been checked in their own merge groups.
+var mergeGroupRefPattern = regexp.MustCompile(`^refs/heads/gh-readonly-queue/.+/pr-(\d+)-[0-9a-f]+$`)
+
+// parseMergeGroupRef returns a number of a pull request which the merge group was created for.
+func parseMergeGroupRef(ref string) (int, error) {
+	m := mergeGroupRefPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return 0, fmt.Errorf("invalid head ref of a merge group %q: no pull request number is found", ref)
+	}
+	return strconv.Atoi(m[1])
+}
+
+// runMergeGroup enforces the size policy on the pull request of a merge group by a check run on the head commit of the
+// merge group.  The command exits successfully even if the pull request exceeds the maximum size, so that only the
+// check run decides whether the pull request can be merged.
+func runMergeGroup(ctx context.Context, payload []byte) error {
+	logger := log.FromContext(ctx)
+
+	var event mergeGroupEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("unable to unmarshal an event payload to JSON: %w", err)
+	}
+	if event.Action != "checks_requested" {
+		logger.Info("Ignored a merge group event", "action", event.Action)
+		return nil
+	}
+
+	owner := event.Repo.GetOwner().GetLogin()
+	repo := event.Repo.GetName()
+	number, err := parseMergeGroupRef(event.MergeGroup.HeadRef)
+	if err != nil {
+		return err
+	}
+	logger = logger.WithValues("owner", owner, "repo", repo, "number", number, "sha", event.MergeGroup.HeadSHA)
+	ctx = log.IntoContext(ctx, logger)
+	logger.Info("Successfully read an event payload")
+
+	client, _, err := newClient(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	pr, _, err := client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return fmt.Errorf("unable to get a pull request: %w", err)
+	}
+	cfg, err := loadConfig(ctx, client, owner, repo, pr.GetBase().GetRef())
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+
+	// The merge group is created by GitHub from approved pull requests, so its head is as trusted as the base branch.
+	m, err := measurePullRequest(ctx, client, cfg, sizer, owner, repo, pr, event.MergeGroup.HeadSHA, true)
+	if err != nil {
+		return err
+	}
+	if err := actions.AppendSummary(m.report.Markdown()); err != nil {
+		return fmt.Errorf("unable to write a job summary: %w", err)
+	}
+
+	if dryRun {
+		logger.Info("Skipped reporting the size since dry-run is enabled", "size", m.size.String())
+	} else {
+		if err := createCheckRun(ctx, client, owner, repo, event.MergeGroup.HeadSHA, m); err != nil {
+			return err
+		}
+		if commitStatus {
+			if err := setCommitStatus(ctx, client, owner, repo, event.MergeGroup.HeadSHA, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch m.verdict.Result {
+	case policy.Warn:
+		actions.Warning(m.verdict.Message)
+	case policy.Fail:
+		actions.Error(m.verdict.Message)
+	}
+	return nil
+}
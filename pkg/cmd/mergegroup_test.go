@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMergeGroupRef(t *testing.T) {
+	got, err := parseMergeGroupRef("refs/heads/gh-readonly-queue/release/v1/pr-42-0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+	assert.Equal(t, 42, got)
+
+	_, err = parseMergeGroupRef("refs/heads/main")
+	assert.ErrorContains(t, err, `invalid head ref of a merge group "refs/heads/main": no pull request number is found`)
+}
+
+func TestMergeGroup(t *testing.T) {
+	var setup = func(t *testing.T, action string) *[]github.CreateCheckRunOptions {
+		t.Setenv("GITHUB_EVENT_NAME", "merge_group")
+		writePayload(t, &mergeGroupEvent{
+			Action: action,
+			MergeGroup: mergeGroup{
+				HeadSHA: "fff",
+				HeadRef: "refs/heads/gh-readonly-queue/master/pr-42-abc",
+				BaseRef: "refs/heads/master",
+			},
+			Repo: &github.Repository{
+				Owner: &github.User{Login: github.String("kkohtaka")},
+				Name:  github.String("gh-actions-pr-size"),
+			},
+		})
+
+		configFile = ""
+		dryRun = false
+		maxSize = "M"
+		checkRun = false
+		commitStatus = false
+		t.Cleanup(func() {
+			maxSize = ""
+		})
+		httpmock.Activate()
+		t.Cleanup(httpmock.DeactivateAndReset)
+
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42",
+			httpmock.NewJsonResponderOrPanic(200, &github.PullRequest{
+				Number: github.Int(42),
+				Base:   &github.PullRequestBranch{Ref: github.String("master"), SHA: github.String("def")},
+				Head:   &github.PullRequestBranch{Ref: github.String("feature"), SHA: github.String("abc")},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/fff",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewJsonResponderOrPanic(200, []github.CommitFile{
+				{Additions: github.Int(100), Deletions: github.Int(200)},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{}),
+		)
+		var got []github.CreateCheckRunOptions
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/check-runs",
+			func(req *http.Request) (*http.Response, error) {
+				var opts github.CreateCheckRunOptions
+				if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+					return nil, err
+				}
+				got = append(got, opts)
+				return httpmock.NewJsonResponse(201, &github.CheckRun{ID: github.Int64(1)})
+			},
+		)
+		return &got
+	}
+
+	t.Run("The size policy is enforced by a check run.", func(t *testing.T) {
+		got := setup(t, "checks_requested")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		require.Len(t, *got, 1)
+		assert.Equal(t, "PR size", (*got)[0].Name)
+		assert.Equal(t, "fff", (*got)[0].HeadSHA)
+		assert.Equal(t, "failure", (*got)[0].GetConclusion())
+	})
+
+	t.Run("Dry-run is enabled.", func(t *testing.T) {
+		got := setup(t, "checks_requested")
+		dryRun = true
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, *got)
+	})
+
+	t.Run("Other actions are ignored.", func(t *testing.T) {
+		got := setup(t, "destroyed")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, *got)
+	})
+}
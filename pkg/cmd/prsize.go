@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/actions"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/config"
 	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/policy"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/report"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -19,17 +23,304 @@ var PRSizeCmd = &cobra.Command{
 	Use:   "pr-size",
 	Short: "pr-size is a GitHub action for labeling Pull Requests's size",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := bindInputs(cmd.Flags()); err != nil {
+			return err
+		}
 		return runPRSize(cmd.Context())
 	},
 }
 
+var (
+	configFile      string
+	includePatterns []string
+	excludePatterns []string
+	thresholds      []int
+	labelPrefix     string
+	labelTemplate   string
+	token           string
+	apiURL          string
+	appID           int64
+	appPrivateKey   string
+	maxRetries      int
+	maxWait         time.Duration
+	cacheDir        string
+	dryRun          bool
+	comment         bool
+	maxSize         string
+	bypassLabel     string
+	bypassKeyword   string
+	checkRun        bool
+	commitStatus    bool
+	syncLabels      bool
+)
+
+const (
+	// eventPullRequest is a name of an event triggered by activities on pull requests.
+	eventPullRequest = "pull_request"
+	// eventPullRequestTarget is a name of an event triggered by activities on pull requests, which runs in the context
+	// of the base branch with a writable token even for pull requests from forks.
+	eventPullRequestTarget = "pull_request_target"
+	// eventIssueComment is a name of an event triggered by comments on issues and pull requests, which runs in the
+	// context of the default branch.
+	eventIssueComment = "issue_comment"
+	// eventMergeGroup is a name of an event triggered when pull requests are added to a merge queue.
+	eventMergeGroup = "merge_group"
+	// eventSchedule is a name of an event triggered by a scheduled workflow.
+	eventSchedule = "schedule"
+	// eventWorkflowDispatch is a name of an event triggered manually.
+	eventWorkflowDispatch = "workflow_dispatch"
+)
+
+const (
+	// checkRunName is a name of a check run which reports the size of a pull request.
+	checkRunName = "PR size"
+	// statusContext is a context of a commit status which reports the size of a pull request.
+	statusContext = "pr-size"
+)
+
+// ExitCodeSizeExceeded is an exit code of the command when a pull request exceeds the maximum size.
+const ExitCodeSizeExceeded = 2
+
+// ExitError is an error with an exit code of the command.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func init() {
+	PRSizeCmd.PersistentFlags().StringVar(
+		&configFile,
+		"config-file",
+		"",
+		fmt.Sprintf(
+			"path to a local configuration file; if empty, %q is read from the base branch of the pull request",
+			config.DefaultPath,
+		),
+	)
+	PRSizeCmd.PersistentFlags().StringSliceVar(
+		&includePatterns,
+		"include",
+		nil,
+		"glob patterns of files counted in the size, in addition to ones in the configuration",
+	)
+	PRSizeCmd.PersistentFlags().StringSliceVar(
+		&excludePatterns,
+		"exclude",
+		nil,
+		"glob patterns of files not counted in the size, in addition to ones in the configuration",
+	)
+	PRSizeCmd.PersistentFlags().IntSliceVar(
+		&thresholds,
+		"thresholds",
+		nil,
+		"inclusive upper bounds of changed lines of the tiers except the last one, overriding the configuration",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&labelPrefix,
+		"label-prefix",
+		"",
+		"prefix of labels of tiers which have no explicit labels, overriding the configuration",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&labelTemplate,
+		"label-template",
+		"",
+		"Go template of labels of tiers which have no explicit labels (e.g. \"pr-size: {{.Name}}\"), overriding the configuration",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&token,
+		"token",
+		"",
+		"token to access GitHub API; if empty, GITHUB_TOKEN environment variable is used",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&apiURL,
+		"api-url",
+		"",
+		"URL of GitHub API (e.g. \"https://github.example.com/api/v3\" for GitHub Enterprise Server); if empty, "+
+			"GITHUB_API_URL environment variable is used",
+	)
+	PRSizeCmd.PersistentFlags().Int64Var(
+		&appID,
+		"app-id",
+		0,
+		"ID of a GitHub App to authenticate as its installation instead of the token; if 0, GITHUB_APP_ID environment "+
+			"variable is used",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&appPrivateKey,
+		"app-private-key",
+		"",
+		"private key of the GitHub App in PEM; if empty, GITHUB_APP_PRIVATE_KEY environment variable is used",
+	)
+	PRSizeCmd.PersistentFlags().IntVar(
+		&maxRetries,
+		"max-retries",
+		gh.DefaultMaxRetries,
+		"maximum number of times a request to GitHub API failed by rate limits or server errors is retried",
+	)
+	PRSizeCmd.PersistentFlags().DurationVar(
+		&maxWait,
+		"max-wait",
+		gh.DefaultMaxWait,
+		"maximum wait for a rate limit of GitHub API before a request is sent or retried; a request which needs a "+
+			"longer wait fails, and if 0, waits are not limited",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&cacheDir,
+		"cache-dir",
+		"",
+		"directory in which responses of GitHub API are cached to send conditional requests; if empty, responses are "+
+			"cached only in memory",
+	)
+	PRSizeCmd.PersistentFlags().BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"compute the size of the pull request without changing anything on GitHub",
+	)
+	PRSizeCmd.PersistentFlags().BoolVar(
+		&comment,
+		"comment",
+		false,
+		"post a comment with a breakdown of the size on the pull request, overriding the configuration",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&maxSize,
+		"max-size",
+		"",
+		"name of the largest acceptable tier; the command fails for larger pull requests after labeling them",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&bypassLabel,
+		"bypass-label",
+		"",
+		"label which exempts a pull request from the maximum size",
+	)
+	PRSizeCmd.PersistentFlags().StringVar(
+		&bypassKeyword,
+		"bypass-keyword",
+		"",
+		"keyword in a body of a pull request which exempts the pull request from the maximum size",
+	)
+	PRSizeCmd.PersistentFlags().BoolVar(
+		&checkRun,
+		"check-run",
+		false,
+		"report the size as a check run on the head commit of the pull request",
+	)
+	PRSizeCmd.PersistentFlags().BoolVar(
+		&commitStatus,
+		"commit-status",
+		false,
+		"report the size as a commit status on the head commit of the pull request, for tokens without Checks API",
+	)
+	PRSizeCmd.PersistentFlags().BoolVar(
+		&syncLabels,
+		"sync-labels",
+		false,
+		"create labels of tiers in the repository and update their colors and descriptions before labeling",
+	)
+}
+
+// loadConfig reads a configuration from a local file if it's specified, or from the base branch of the pull request
+// otherwise.  If no configuration file exists on the base branch, the default configuration is returned.
+func loadConfig(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, ref string,
+) (*config.Config, error) {
+	return loadConfigFromTree(ctx, gh.NewRepositoryTree(client, owner, repo, ref), ref)
+}
+
+// loadConfigFromTree reads a configuration from a local file if it's specified, or from the tree at the ref otherwise.
+// If no configuration file exists in the tree, the default configuration is returned.
+func loadConfigFromTree(ctx context.Context, tree gh.Tree, ref string) (*config.Config, error) {
+	logger := log.FromContext(ctx)
+
+	var c *config.Config
+	if configFile != "" {
+		var err error
+		c, err = config.Load(configFile)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := tree.ReadFile(ctx, config.DefaultPath)
+		switch {
+		case errors.Is(err, gh.ErrFileNotFound):
+			logger.Info("No configuration file was found, so the default configuration is used",
+				"path", config.DefaultPath,
+				"ref", ref,
+			)
+			c = config.Default()
+		case err != nil:
+			return nil, fmt.Errorf("get a configuration file: %w", err)
+		default:
+			c, err = config.Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("parse a configuration file at %q on %q: %w", config.DefaultPath, ref, err)
+			}
+		}
+	}
+
+	c.Include = append(c.Include, includePatterns...)
+	c.Exclude = append(c.Exclude, excludePatterns...)
+	if labelPrefix != "" {
+		c.LabelPrefix = labelPrefix
+		c.LabelTemplate = ""
+	}
+	if labelTemplate != "" {
+		c.LabelTemplate = labelTemplate
+	}
+	if comment {
+		c.Comment.Enabled = true
+	}
+	if syncLabels {
+		c.Labels.Sync = true
+	}
+	if maxSize != "" {
+		c.Policy.MaxSize = maxSize
+	}
+	if bypassLabel != "" {
+		c.Policy.BypassLabel = bypassLabel
+	}
+	if bypassKeyword != "" {
+		c.Policy.BypassKeyword = bypassKeyword
+	}
+	if len(thresholds) > 0 {
+		if err := c.SetThresholds(thresholds); err != nil {
+			return nil, fmt.Errorf("override thresholds: %w", err)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 func runPRSize(ctx context.Context) error {
 	logger := log.FromContext(ctx)
 
-	if eventType := os.Getenv("GITHUB_EVENT_NAME"); eventType != "pull_request" {
+	eventType := os.Getenv("GITHUB_EVENT_NAME")
+	switch eventType {
+	case eventPullRequest, eventPullRequestTarget, eventIssueComment, eventMergeGroup:
+	case eventSchedule, eventWorkflowDispatch:
+		// These events don't belong to any pull request, so all open pull requests are relabeled.
+		return runBackfill(ctx)
+	default:
 		return fmt.Errorf(
-			"unsupported event type %q is specified: event types other than \"pull_request\" is not supported",
-			eventType,
+			"unsupported event type %q is specified: event types other than %q, %q, %q, %q, %q and %q are not supported",
+			eventType, eventPullRequest, eventPullRequestTarget, eventIssueComment, eventMergeGroup, eventSchedule,
+			eventWorkflowDispatch,
 		)
 	}
 
@@ -43,6 +334,13 @@ func runPRSize(ctx context.Context) error {
 		return fmt.Errorf("unable to read an event file at %q: %w", eventPath, err)
 	}
 
+	switch eventType {
+	case eventIssueComment:
+		return runIssueComment(ctx, payload)
+	case eventMergeGroup:
+		return runMergeGroup(ctx, payload)
+	}
+
 	var event github.PullRequestEvent
 	err = json.Unmarshal(payload, &event)
 	if err != nil {
@@ -59,27 +357,295 @@ func runPRSize(ctx context.Context) error {
 		"number", number,
 	)
 
-	var tc *http.Client
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
+	client, author, err := newClient(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	attributesRef := event.GetPullRequest().GetHead().GetSHA()
+	if eventType == eventPullRequestTarget {
+		attributesRef = trustedAttributesRef(event.GetPullRequest())
+	}
+	cfg, err := loadConfig(ctx, client, owner, repo, event.GetPullRequest().GetBase().GetRef())
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	return runPullRequest(ctx, client, author, cfg, owner, repo, event.GetPullRequest(), attributesRef)
+}
+
+// trustedAttributesRef returns a ref from which .gitattributes files of the pull request are read when running with a
+// writable token, e.g. on pull_request_target, which runs so even for pull requests from forks.  The head of a pull
+// request from a fork isn't trusted, so that contributors can't exclude their changes from the size by .gitattributes,
+// and attributes are read from the base branch instead.
+func trustedAttributesRef(pr *github.PullRequest) string {
+	if pr.GetHead().GetRepo().GetID() != pr.GetBase().GetRepo().GetID() {
+		return pr.GetBase().GetSHA()
+	}
+	return pr.GetHead().GetSHA()
+}
+
+// runPullRequest computes a size of the pull request, reports it and updates the pull request.  Attributes of files
+// are read from .gitattributes files at attributesRef.
+func runPullRequest(
+	ctx context.Context,
+	client *github.Client,
+	author *author,
+	cfg *config.Config,
+	owner, repo string,
+	pr *github.PullRequest,
+	attributesRef string,
+) error {
+	logger := log.FromContext(ctx)
+
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+
+	m, err := measurePullRequest(ctx, client, cfg, sizer, owner, repo, pr, attributesRef, false)
+	if err != nil {
+		return err
+	}
+	size, changed, verdict := m.size, m.report.Changes, m.verdict
+
+	err = actions.SetOutputs(
+		actions.Output{Name: "size", Value: size.String()},
+		actions.Output{Name: "label", Value: size.GetLabel()},
+		actions.Output{Name: "changed_lines", Value: strconv.Itoa(changed.Total())},
+		actions.Output{Name: "additions", Value: strconv.Itoa(changed.Additions)},
+		actions.Output{Name: "deletions", Value: strconv.Itoa(changed.Deletions)},
+		actions.Output{Name: "files", Value: strconv.Itoa(changed.FileCount())},
+		actions.Output{Name: "excluded_lines", Value: strconv.Itoa(changed.ExcludedLines())},
+		actions.Output{Name: "incomplete", Value: strconv.FormatBool(changed.Truncated)},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to set outputs: %w", err)
+	}
+
+	if err := actions.AppendSummary(m.report.Markdown()); err != nil {
+		return fmt.Errorf("unable to write a job summary: %w", err)
+	}
+	if changed.Truncated {
+		actions.Warning(m.report.IncompleteWarning())
+	}
+
+	if dryRun {
+		logger.Info("Skipped updating the pull request since dry-run is enabled", "size", size.String())
+	} else {
+		if cfg.Labels.Sync {
+			if _, err := gh.SyncLabels(ctx, client, owner, repo, sizer.Tiers()); err != nil {
+				return fmt.Errorf("unable to sync labels: %w", err)
+			}
+		}
+		if err := applyMeasurement(ctx, client, author, cfg, sizer, owner, repo, pr, m); err != nil {
+			return err
+		}
+	}
+
+	switch verdict.Result {
+	case policy.Warn:
+		actions.Warning(verdict.Message)
+	case policy.Fail:
+		actions.Error(verdict.Message)
+		return &ExitError{Code: ExitCodeSizeExceeded, Err: errors.New(verdict.Message)}
+	}
+	return nil
+}
+
+// measurement is a size of a pull request and a verdict of the size policy on it.
+type measurement struct {
+	size    gh.Tier
+	report  *report.Report
+	verdict policy.Verdict
+}
+
+// measurePullRequest computes a size of the pull request and evaluates the size policy on it.  Attributes of files are
+// read from .gitattributes files at attributesRef.  If breakdown is true, files of the pull request are always listed
+// for a report which shows them, e.g. annotations of a check run.
+func measurePullRequest(
+	ctx context.Context,
+	client *github.Client,
+	cfg *config.Config,
+	sizer *gh.Sizer,
+	owner, repo string,
+	pr *github.PullRequest,
+	attributesRef string,
+	breakdown bool,
+) (*measurement, error) {
+	logger := log.FromContext(ctx).WithValues("owner", owner, "repo", repo, "number", pr.GetNumber())
+
+	changed, err := countChangedLines(ctx, client, cfg, owner, repo, pr, attributesRef, breakdown)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed.Excluded) > 0 {
+		logger.Info("Some files were excluded from the size of a pull request",
+			"files", len(changed.Excluded),
+			"lines", changed.ExcludedLines(),
 		)
-		tc = oauth2.NewClient(ctx, ts)
 	}
-	client := github.NewClient(tc)
 
-	changed, err := gh.GetPullRequestChangedLines(ctx, client, owner, repo, number)
+	size := sizer.Size(changed.Total())
+	logger.Info("Got a size of a pull request",
+		"size", size.String(),
+		"changed", changed.Total(),
+		"excluded", changed.ExcludedLines(),
+	)
+
+	// Comments are listed for commands only if the pull request may have any.  Its payload tells the number of comments
+	// except when it's listed, e.g. by a backfill.
+	var override *gh.Override
+	if pr.Comments == nil || pr.GetComments() > 0 {
+		override, err = gh.FindOverride(ctx, client, owner, repo, pr.GetNumber(), sizer)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find an override of the size: %w", err)
+		}
+	}
+	if override != nil {
+		size = override.Tier
+		logger.Info("The size of a pull request is overridden", "size", size.String(), "user", override.User)
+	}
+
+	verdict := cfg.Policy.Evaluate(sizer, size, pr)
+	logger.Info("Evaluated a size policy", "result", verdict.Result.String(), "message", verdict.Message)
+
+	return &measurement{
+		size:    size,
+		report:  &report.Report{Size: size, Tiers: sizer.Tiers(), Changes: changed, Override: override},
+		verdict: verdict,
+	}, nil
+}
+
+// countChangedLines returns the number of changed lines in the pull request.  If no files can be excluded and none of
+// the caller, a comment and a check run needs a breakdown by files, the totals of the pull request are used without
+// listing its files.  A job summary doesn't need it, and is written without the largest files instead.
+func countChangedLines(
+	ctx context.Context,
+	client *github.Client,
+	cfg *config.Config,
+	owner, repo string,
+	pr *github.PullRequest,
+	attributesRef string,
+	breakdown bool,
+) (*gh.ChangedLines, error) {
+	logger := log.FromContext(ctx).WithValues("owner", owner, "repo", repo, "number", pr.GetNumber())
+
+	globFilter, err := cfg.GlobFilter()
 	if err != nil {
-		return fmt.Errorf("unable to get the number of changed lines in a pull request: %w", err)
+		return nil, fmt.Errorf("unable to load a configuration: %w", err)
 	}
+	linguist := gh.NewLinguistFilter(client, owner, repo, attributesRef)
 
-	size := gh.NewSize(changed)
-	logger.Info("Got a size of a pull request", "size", size.String())
+	breakdown = breakdown || cfg.Comment.Enabled || checkRun
+	if totals, ok := gh.PullRequestTotals(pr); ok && globFilter.Empty() && !breakdown {
+		empty, err := linguist.Empty(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list .gitattributes files: %w", err)
+		}
+		if empty {
+			logger.Info("Used the totals of a pull request since no files can be excluded")
+			return totals, nil
+		}
+	}
 
-	err = gh.SetLabelOnPullRequest(ctx, client, owner, repo, number, size)
+	changed, err := gh.GetPullRequestChangedLines(ctx, client, owner, repo, pr, globFilter, linguist)
 	if err != nil {
+		return nil, fmt.Errorf("unable to get the number of changed lines in a pull request: %w", err)
+	}
+	return changed, nil
+}
+
+// applyMeasurement labels the pull request with its size, and updates a comment as the author, a check run and a commit
+// status if they're enabled.
+func applyMeasurement(
+	ctx context.Context,
+	client *github.Client,
+	author *author,
+	cfg *config.Config,
+	sizer *gh.Sizer,
+	owner, repo string,
+	pr *github.PullRequest,
+	m *measurement,
+) error {
+	logger := log.FromContext(ctx).WithValues("owner", owner, "repo", repo, "number", pr.GetNumber())
+	number := pr.GetNumber()
+
+	if err := gh.SetLabelOnPullRequest(ctx, client, owner, repo, number, sizer, m.size); err != nil {
 		return fmt.Errorf("unable to set a label on a pull request: %w", err)
 	}
-	logger.Info("Set a label to represent a pull request size", "size", size.String())
+	logger.Info("Set a label to represent a pull request size", "size", m.size.String())
+
+	if cfg.Comment.Enabled {
+		if err := updateComment(ctx, client, author, owner, repo, number, cfg.Comment, sizer, m.report); err != nil {
+			return fmt.Errorf("unable to update a comment on a pull request: %w", err)
+		}
+	}
+
+	if checkRun {
+		if err := createCheckRun(ctx, client, owner, repo, pr.GetHead().GetSHA(), m); err != nil {
+			return err
+		}
+	}
+	if commitStatus {
+		if err := setCommitStatus(ctx, client, owner, repo, pr.GetHead().GetSHA(), m); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// createCheckRun reports the measurement as a check run on the commit.
+func createCheckRun(ctx context.Context, client *github.Client, owner, repo, sha string, m *measurement) error {
+	err := gh.CreateCheckRun(ctx, client, owner, repo, &gh.CheckRun{
+		Name:        checkRunName,
+		HeadSHA:     sha,
+		Conclusion:  m.verdict.Result.Conclusion(),
+		Title:       m.report.Title(),
+		Summary:     m.verdict.Message + "\n\n" + m.report.Markdown(),
+		Annotations: m.report.Annotations(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create a check run: %w", err)
+	}
+	return nil
+}
+
+// setCommitStatus reports the measurement as a commit status on the commit.
+func setCommitStatus(ctx context.Context, client *github.Client, owner, repo, sha string, m *measurement) error {
+	err := gh.SetCommitStatus(ctx, client, owner, repo, sha, &gh.CommitStatus{
+		Context:     statusContext,
+		State:       m.verdict.Result.State(),
+		Description: m.report.Title(),
+		TargetURL:   actions.RunURL(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to set a commit status: %w", err)
+	}
+	return nil
+}
+
+// updateComment posts a comment with the report on the pull request, or deletes the comment if the pull request is
+// smaller than the threshold and the configuration says so.
+func updateComment(
+	ctx context.Context,
+	client *github.Client,
+	author *author,
+	owner, repo string,
+	number int,
+	c config.Comment,
+	sizer *gh.Sizer,
+	r *report.Report,
+) error {
+	var threshold *gh.Tier
+	if i := sizer.Index(c.Threshold); i >= 0 {
+		threshold = &sizer.Tiers()[i]
+	}
+	login, err := author.login(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to resolve a login of the commenter: %w", err)
+	}
+	if threshold != nil && c.DeleteBelowThreshold && r.Size.Max < threshold.Max {
+		return gh.DeleteStickyComment(ctx, client, owner, repo, number, login)
+	}
+	return gh.SetStickyComment(ctx, client, owner, repo, number, login, r.Comment(threshold))
+}
@@ -2,48 +2,103 @@ package cmd
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-github/v29/github"
 	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// writeEvent writes a payload of a pull_request event to a file at GITHUB_EVENT_PATH.
+func writeEvent(t *testing.T, pr *github.PullRequest) {
+	t.Helper()
+
+	writePayload(t, &github.PullRequestEvent{
+		Repo: &github.Repository{
+			Owner: &github.User{
+				Login: github.String("kkohtaka"),
+			},
+			Name: github.String("gh-actions-pr-size"),
+		},
+		PullRequest: pr,
+	})
+}
+
+func writePayload(t *testing.T, event interface{}) {
+	t.Helper()
+
+	f, err := os.CreateTemp("", "event-*.json")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(f.Name())
+	})
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	_, err = f.Write(data)
+	require.NoError(t, err)
+	t.Setenv("GITHUB_EVENT_PATH", f.Name())
+}
+
 func TestPRSize(t *testing.T) {
+	var gotCreatedLabels []string
 	var setup = func(t *testing.T) {
 		t.Setenv("GITHUB_EVENT_NAME", "pull_request")
+		t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary"))
 
-		f, err := os.CreateTemp("", "event-*.json")
-		require.NoError(t, err)
-		t.Cleanup(func() {
-			os.RemoveAll(f.Name())
-		})
-		event := &github.PullRequestEvent{
-			Repo: &github.Repository{
-				Owner: &github.User{
-					Login: github.String("kkohtaka"),
-				},
-				Name: github.String("gh-actions-pr-size"),
+		writeEvent(t, &github.PullRequest{
+			Number: github.Int(42),
+			Base: &github.PullRequestBranch{
+				Ref: github.String("master"),
 			},
-			PullRequest: &github.PullRequest{
-				Number: github.Int(42),
+			Head: &github.PullRequestBranch{
+				SHA: github.String("abc"),
 			},
-		}
-		data, err := json.Marshal(event)
-		require.NoError(t, err)
-		_, err = f.Write(data)
-		require.NoError(t, err)
-		t.Setenv("GITHUB_EVENT_PATH", f.Name())
+		})
 
+		configFile = ""
+		includePatterns = nil
+		excludePatterns = nil
+		thresholds = nil
+		labelPrefix = ""
+		labelTemplate = ""
+		token = ""
+		dryRun = false
+		comment = false
+		maxSize = ""
+		bypassLabel = ""
+		bypassKeyword = ""
+		checkRun = false
+		commitStatus = false
+		syncLabels = false
+		concurrency = defaultConcurrency
 		httpmock.Activate()
 		t.Cleanup(func() {
 			httpmock.DeactivateAndReset()
 		})
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/user",
+			httpmock.NewStringResponder(403, `{"message": "Resource not accessible by integration"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/abc",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
 		httpmock.RegisterResponder(
 			"GET",
 			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
@@ -65,7 +120,13 @@ func TestPRSize(t *testing.T) {
 			},
 		)
 
-		var gotCreatedLabels []string
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{}),
+		)
+
+		gotCreatedLabels = nil
 		httpmock.RegisterResponder(
 			"POST",
 			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
@@ -87,6 +148,513 @@ func TestPRSize(t *testing.T) {
 		setup(t)
 		err := PRSizeCmd.ExecuteContext(context.Background())
 		require.NoError(t, err)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
+	})
+
+	t.Run("A configuration file exists on the base branch.", func(t *testing.T) {
+		setup(t)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			func(req *http.Request) (*http.Response, error) {
+				if ref := req.URL.Query().Get("ref"); ref != "master" {
+					return httpmock.NewStringResponse(400, fmt.Sprintf("unexpected ref %q", ref)), nil
+				}
+				return httpmock.NewJsonResponse(200, github.RepositoryContent{
+					Type:     github.String("file"),
+					Encoding: github.String("base64"),
+					Content: github.String(base64.StdEncoding.EncodeToString([]byte(
+						"tiers:\n- name: small\n  max: 500\n- name: large\n",
+					))),
+				})
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/small"}, gotCreatedLabels)
+	})
+
+	t.Run("A local configuration file is specified.", func(t *testing.T) {
+		setup(t)
+		configFile = filepath.Join(t.TempDir(), "pr-size.yaml")
+		require.NoError(t, os.WriteFile(
+			configFile,
+			[]byte("tiers:\n- name: small\n  max: 100\n  label: small\n- name: large\n  label: large\n"),
+			0o644,
+		))
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"large"}, gotCreatedLabels)
+	})
+
+	t.Run("Exclude patterns are specified.", func(t *testing.T) {
+		setup(t)
+		excludePatterns = []string{"**/go.sum"}
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewJsonResponderOrPanic(200, []github.CommitFile{
+				{
+					Filename:  github.String("main.go"),
+					Additions: github.Int(10),
+				},
+				{
+					Filename:  github.String("go.sum"),
+					Additions: github.Int(100),
+				},
+			}),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/S"}, gotCreatedLabels)
+	})
+
+	t.Run("The totals of the pull request are used without listing files.", func(t *testing.T) {
+		setup(t)
+		writeEvent(t, &github.PullRequest{
+			Number:       github.Int(42),
+			Base:         &github.PullRequestBranch{Ref: github.String("master")},
+			Head:         &github.PullRequestBranch{SHA: github.String("abc")},
+			Additions:    github.Int(5),
+			Deletions:    github.Int(3),
+			ChangedFiles: github.Int(2),
+		})
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewErrorResponder(errors.New("files must not be listed")),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/XS"}, gotCreatedLabels)
+	})
+
+	t.Run("Files are listed when .gitattributes files exist.", func(t *testing.T) {
+		setup(t)
+		writeEvent(t, &github.PullRequest{
+			Number:       github.Int(42),
+			Base:         &github.PullRequestBranch{Ref: github.String("master")},
+			Head:         &github.PullRequestBranch{SHA: github.String("abc")},
+			Additions:    github.Int(5),
+			Deletions:    github.Int(3),
+			ChangedFiles: github.Int(2),
+		})
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/abc",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{
+				Entries: []github.TreeEntry{
+					{Path: github.String("docs/.gitattributes"), Type: github.String("blob")},
+				},
+			}),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
+	})
+
+	t.Run("Action inputs are specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("INPUT_THRESHOLDS", "1000,2000,3000,4000,5000")
+		t.Setenv("INPUT_LABEL-PREFIX", "pr-size: ")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pr-size: XS"}, gotCreatedLabels)
+	})
+
+	t.Run("A label template is specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("INPUT_LABEL-TEMPLATE", "📏 {{.Name}}")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"📏 L"}, gotCreatedLabels)
+	})
+
+	t.Run("An override by a command is respected.", func(t *testing.T) {
+		setup(t)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{
+				{User: &github.User{Login: github.String("maintainer")}, Body: github.String("/size override XS")},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/maintainer/permission",
+			httpmock.NewJsonResponderOrPanic(200, &github.RepositoryPermissionLevel{Permission: github.String("write")}),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/XS"}, gotCreatedLabels)
+	})
+
+	t.Run("Comments aren't listed for commands when the pull request has no comments.", func(t *testing.T) {
+		setup(t)
+		writeEvent(t, &github.PullRequest{
+			Number:   github.Int(42),
+			Base:     &github.PullRequestBranch{Ref: github.String("master")},
+			Head:     &github.PullRequestBranch{SHA: github.String("abc")},
+			Comments: github.Int(0),
+		})
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewErrorResponder(errors.New("comments must not be listed")),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
+	})
+
+	t.Run("Invalid thresholds are specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("INPUT_THRESHOLDS", "1000")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, "override thresholds: 5 thresholds are required for 6 tiers, but 1 are specified")
+	})
+
+	t.Run("Outputs are written to GITHUB_OUTPUT.", func(t *testing.T) {
+		setup(t)
+		output := filepath.Join(t.TempDir(), "output")
+		t.Setenv("GITHUB_OUTPUT", output)
+		excludePatterns = []string{"**/go.sum"}
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewJsonResponderOrPanic(200, []github.CommitFile{
+				{
+					Filename:  github.String("main.go"),
+					Additions: github.Int(10),
+					Deletions: github.Int(5),
+				},
+				{
+					Filename:  github.String("go.sum"),
+					Additions: github.Int(100),
+				},
+			}),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(output)
+		require.NoError(t, err)
+		got := map[string]string{}
+		lines := strings.Split(string(data), "\n")
+		for i := 0; i+2 < len(lines); i += 3 {
+			name, _, _ := strings.Cut(lines[i], "<<")
+			got[name] = lines[i+1]
+		}
+		assert.Equal(t, map[string]string{
+			"size":           "S",
+			"label":          "size/S",
+			"changed_lines":  "15",
+			"additions":      "10",
+			"deletions":      "5",
+			"files":          "1",
+			"excluded_lines": "100",
+			"incomplete":     "false",
+		}, got)
+	})
+
+	t.Run("A job summary is written to GITHUB_STEP_SUMMARY.", func(t *testing.T) {
+		setup(t)
+		summary := filepath.Join(t.TempDir(), "summary")
+		t.Setenv("GITHUB_STEP_SUMMARY", summary)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(summary)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "## Pull request size: L\n")
+	})
+
+	t.Run("A job summary is written without listing files.", func(t *testing.T) {
+		setup(t)
+		summary := filepath.Join(t.TempDir(), "summary")
+		t.Setenv("GITHUB_STEP_SUMMARY", summary)
+		writeEvent(t, &github.PullRequest{
+			Number:       github.Int(42),
+			Base:         &github.PullRequestBranch{Ref: github.String("master")},
+			Head:         &github.PullRequestBranch{SHA: github.String("abc")},
+			Additions:    github.Int(5),
+			Deletions:    github.Int(3),
+			ChangedFiles: github.Int(2),
+		})
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewErrorResponder(errors.New("files must not be listed")),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/XS"}, gotCreatedLabels)
+		assert.Zero(t,
+			httpmock.GetCallCountInfo()["GET https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files"],
+		)
+
+		data, err := os.ReadFile(summary)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "## Pull request size: XS\n")
+		assert.Contains(t, string(data), "The largest files are not listed")
+		assert.NotContains(t, string(data), "### Largest files")
+	})
+
+	t.Run("A comment is enabled.", func(t *testing.T) {
+		setup(t)
+		comment = true
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []github.IssueComment{}),
+		)
+		var gotComments []string
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			func(req *http.Request) (*http.Response, error) {
+				var c github.IssueComment
+				if err := json.NewDecoder(req.Body).Decode(&c); err != nil {
+					return nil, err
+				}
+				gotComments = append(gotComments, c.GetBody())
+				return httpmock.NewJsonResponse(201, c)
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		require.Len(t, gotComments, 1)
+		assert.Contains(t, gotComments[0], "### Pull request size: L\n")
+	})
+
+	t.Run("A comment is deleted below the threshold.", func(t *testing.T) {
+		setup(t)
+		configFile = filepath.Join(t.TempDir(), "pr-size.yaml")
+		require.NoError(t, os.WriteFile(
+			configFile,
+			[]byte("comment: {enabled: true, threshold: XL, deleteBelowThreshold: true}\n"),
+			0o644,
+		))
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []github.IssueComment{
+				{
+					ID:   github.Int64(1),
+					Body: github.String("<!-- gh-actions-pr-size -->\nold"),
+					User: &github.User{Login: github.String("github-actions[bot]")},
+				},
+			}),
+		)
+		var deleted bool
+		httpmock.RegisterResponder(
+			"DELETE",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/comments/1",
+			func(req *http.Request) (*http.Response, error) {
+				deleted = true
+				return httpmock.NewBytesResponse(204, nil), nil
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.True(t, deleted)
+	})
+
+	t.Run("The pull request exceeds the maximum size.", func(t *testing.T) {
+		setup(t)
+		maxSize = "M"
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		var exitErr *ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, ExitCodeSizeExceeded, exitErr.Code)
+		assert.EqualError(t, err, "The pull request size L exceeds the maximum size M.")
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels, "the label must be set even if the policy fails")
+	})
+
+	t.Run("The pull request exceeding the maximum size has the bypass label.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("INPUT_MAX-SIZE", "M")
+		t.Setenv("INPUT_BYPASS-LABEL", "skip-size")
+		writeEvent(t, &github.PullRequest{
+			Number: github.Int(42),
+			Head: &github.PullRequestBranch{
+				SHA: github.String("abc"),
+			},
+			Labels: []*github.Label{{Name: github.String("skip-size")}},
+		})
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("A check run is created.", func(t *testing.T) {
+		setup(t)
+		checkRun = true
+		maxSize = "M"
+		var got github.CreateCheckRunOptions
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/check-runs",
+			func(req *http.Request) (*http.Response, error) {
+				if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+					return nil, err
+				}
+				return httpmock.NewJsonResponse(201, &github.CheckRun{ID: github.Int64(1)})
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		var exitErr *ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, "PR size", got.Name)
+		assert.Equal(t, "abc", got.HeadSHA)
+		assert.Equal(t, "failure", got.GetConclusion())
+		assert.Equal(t, "L — 300 lines in 1 files", got.GetOutput().GetTitle())
+	})
+
+	t.Run("A commit status is created.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("INPUT_COMMIT-STATUS", "true")
+		var got github.RepoStatus
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/statuses/abc",
+			func(req *http.Request) (*http.Response, error) {
+				if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+					return nil, err
+				}
+				return httpmock.NewJsonResponse(201, got)
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "pr-size", got.GetContext())
+		assert.Equal(t, "success", got.GetState())
+		assert.Equal(t, "L — 300 lines in 1 files", got.GetDescription())
+	})
+
+	t.Run("Labels are synced before labeling.", func(t *testing.T) {
+		setup(t)
+		syncLabels = true
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/labels",
+			httpmock.NewJsonResponderOrPanic(200, []*github.Label{}),
+		)
+		var created []string
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/labels",
+			func(req *http.Request) (*http.Response, error) {
+				var label github.Label
+				if err := json.NewDecoder(req.Body).Decode(&label); err != nil {
+					return nil, err
+				}
+				created = append(created, label.GetName())
+				return httpmock.NewJsonResponse(201, label)
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, created, 6)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
+	})
+
+	t.Run("An unknown maximum size is specified.", func(t *testing.T) {
+		setup(t)
+		maxSize = "XXXL"
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, `invalid policy: max size "XXXL" is not a tier`)
+	})
+
+	t.Run("Dry-run is enabled.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("INPUT_DRY-RUN", "true")
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, gotCreatedLabels)
+	})
+
+	t.Run("A configuration file on the base branch is invalid.", func(t *testing.T) {
+		setup(t)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			func(req *http.Request) (*http.Response, error) {
+				return httpmock.NewJsonResponse(200, github.RepositoryContent{
+					Type:     github.String("file"),
+					Encoding: github.String("base64"),
+					Content:  github.String(base64.StdEncoding.EncodeToString([]byte("tiers:\n- max: 10\n"))),
+				})
+			},
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.ErrorContains(t, err, "unable to load a configuration")
+	})
+
+	t.Run("A pull_request_target event of a pull request from a fork is specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("GITHUB_EVENT_NAME", "pull_request_target")
+		writeEvent(t, &github.PullRequest{
+			Number: github.Int(42),
+			Base: &github.PullRequestBranch{
+				Ref:  github.String("master"),
+				SHA:  github.String("def"),
+				Repo: &github.Repository{ID: github.Int64(1)},
+			},
+			Head: &github.PullRequestBranch{
+				Ref:  github.String("feature"),
+				SHA:  github.String("abc"),
+				Repo: &github.Repository{ID: github.Int64(2)},
+			},
+		})
+		var gotConfigRef string
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			func(req *http.Request) (*http.Response, error) {
+				gotConfigRef = req.URL.Query().Get("ref")
+				return httpmock.NewStringResponse(404, `{"message": "Not Found"}`), nil
+			},
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/abc",
+			httpmock.NewErrorResponder(errors.New("the head of the pull request must not be read")),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/def",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "master", gotConfigRef)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
+	})
+
+	t.Run("A pull_request_target event of a pull request in the same repository is specified.", func(t *testing.T) {
+		setup(t)
+		t.Setenv("GITHUB_EVENT_NAME", "pull_request_target")
+		writeEvent(t, &github.PullRequest{
+			Number: github.Int(42),
+			Base: &github.PullRequestBranch{
+				Ref:  github.String("master"),
+				SHA:  github.String("def"),
+				Repo: &github.Repository{ID: github.Int64(1)},
+			},
+			Head: &github.PullRequestBranch{
+				Ref:  github.String("feature"),
+				SHA:  github.String("abc"),
+				Repo: &github.Repository{ID: github.Int64(1)},
+			},
+		})
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/def",
+			httpmock.NewErrorResponder(errors.New("attributes must be read from the head")),
+		)
+		err := PRSizeCmd.ExecuteContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"size/L"}, gotCreatedLabels)
 	})
 
 	t.Run("An unsupported event type is specified.", func(t *testing.T) {
@@ -96,7 +664,8 @@ func TestPRSize(t *testing.T) {
 		require.ErrorContains(
 			t,
 			err,
-			"unsupported event type \"push\" is specified: event types other than \"pull_request\" is not supported",
+			"unsupported event type \"push\" is specified: event types other than \"pull_request\", \"pull_request_target\", "+
+				"\"issue_comment\", \"merge_group\", \"schedule\" and \"workflow_dispatch\" are not supported",
 		)
 	})
 
@@ -171,3 +740,37 @@ func TestPRSize(t *testing.T) {
 		)
 	})
 }
+
+func TestTrustedAttributesRef(t *testing.T) {
+	upstream := &github.Repository{ID: github.Int64(1)}
+	fork := &github.Repository{ID: github.Int64(2)}
+	tcs := []struct {
+		name string
+		head *github.Repository
+		want string
+	}{
+		{
+			name: "The pull request is from a branch of the repository.",
+			head: upstream,
+			want: "head",
+		},
+		{
+			name: "The pull request is from a fork.",
+			head: fork,
+			want: "base",
+		},
+		{
+			name: "The fork of the pull request was deleted.",
+			want: "base",
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trustedAttributesRef(&github.PullRequest{
+				Base: &github.PullRequestBranch{SHA: github.String("base"), Repo: upstream},
+				Head: &github.PullRequestBranch{SHA: github.String("head"), Repo: tt.head},
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,478 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/policy"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve receives webhooks of pull requests and labels them with their sizes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe(cmd.Context())
+	},
+}
+
+const (
+	// defaultServeAddr is the default address on which webhooks are received.
+	defaultServeAddr = ":8080"
+	// maxQueuedEvents is the maximum number of events waiting to be processed, which is divided among workers.
+	// Webhooks are rejected with 503 Service Unavailable while the queue is full, so that they can be redelivered
+	// later.
+	maxQueuedEvents = 100
+	// maxDeliveries is the number of the latest delivery IDs remembered to ignore redelivered webhooks.
+	maxDeliveries = 10000
+	// maxPayloadSize is the maximum size of a payload of a webhook, which GitHub caps at 25 MB.
+	maxPayloadSize = 25 << 20
+	// eventTimeout is the maximum time to process an event.
+	eventTimeout = 5 * time.Minute
+	// shutdownTimeout is the maximum time to wait for events being processed on a shutdown.
+	shutdownTimeout = 30 * time.Second
+	// maxClients is the maximum number of clients of GitHub API kept for installations or repositories.  The least
+	// recently used ones are evicted.
+	maxClients = 100
+	// clientIdleTimeout is the duration after which a client of GitHub API which is not used is evicted.
+	clientIdleTimeout = time.Hour
+
+	headerSignature = "X-Hub-Signature-256"
+	signaturePrefix = "sha256="
+	eventPing       = "ping"
+)
+
+var (
+	serveAddr     string
+	webhookSecret string
+)
+
+// pullRequestActions are actions of pull_request events which change sizes of pull requests or their exemptions.
+var pullRequestActions = map[string]struct{}{
+	"opened":      {},
+	"reopened":    {},
+	"synchronize": {},
+	"edited":      {},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(
+		&serveAddr,
+		"addr",
+		defaultServeAddr,
+		"address on which webhooks are received",
+	)
+	serveCmd.Flags().StringVar(
+		&webhookSecret,
+		"webhook-secret",
+		"",
+		"secret of webhooks to verify their signatures; if empty, GITHUB_WEBHOOK_SECRET environment variable is used",
+	)
+	PRSizeCmd.AddCommand(serveCmd)
+}
+
+// runServe receives webhooks of pull requests until the context is done or a signal is received, and processes them
+// with at most concurrency workers.
+func runServe(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	if concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: it must be positive", concurrency)
+	}
+	secret := webhookSecret
+	if secret == "" {
+		secret = os.Getenv("GITHUB_WEBHOOK_SECRET")
+	}
+	if secret == "" {
+		return errors.New("a secret of webhooks must be specified by --webhook-secret or GITHUB_WEBHOOK_SECRET")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	webhooks := newWebhookServer(ctx, []byte(secret), concurrency)
+	mux := http.NewServeMux()
+	mux.Handle("/", webhooks)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	server := &http.Server{
+		Addr:              serveAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("Receiving webhooks", "addr", serveAddr, "concurrency", concurrency)
+		errCh <- server.ListenAndServe()
+	}()
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutting down the server")
+	case serveErr = <-errCh:
+		serveErr = fmt.Errorf("unable to receive webhooks: %w", serveErr)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error(err, "Failed to shut down the server gracefully")
+	}
+	if err := webhooks.Shutdown(shutdownCtx); err != nil {
+		logger.Error(err, "Gave up waiting for events being processed")
+	}
+	return serveErr
+}
+
+// webhookServer is an http.Handler which receives webhooks of pull_request events, and processes them through the same
+// pipeline as the action with a bounded number of workers.  Events of a pull request are processed by the same worker
+// in the order received, so that an event of an older head never overwrites the result of a newer one.
+type webhookServer struct {
+	secret []byte
+	// events are queues of events by workers.
+	events []chan *webhookEvent
+
+	// ctx is a context of processing events, which is canceled only when a shutdown times out so that events being
+	// processed aren't interrupted by the shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// closed is true once the queues are closed on a shutdown.
+	closed bool
+	// deliveries is a set of the latest delivery IDs, whose order is kept by deliveryRing.
+	deliveries   map[string]struct{}
+	deliveryRing []string
+	next         int
+
+	clientsMu sync.Mutex
+	// clients are clients of GitHub API by installations of a GitHub App or repositories, which are reused so that
+	// tokens are reused across events.
+	clients map[string]*webhookClient
+	// cache is a cache of responses shared by the clients, which is created with the first client.
+	cache *gh.Cache
+}
+
+var _ http.Handler = &webhookServer{}
+
+// webhookEvent is a pull_request event received by a webhook.
+type webhookEvent struct {
+	delivery string
+	event    *github.PullRequestEvent
+}
+
+// webhookClient is a client of GitHub API kept by the server.
+type webhookClient struct {
+	client   *github.Client
+	author   *author
+	lastUsed time.Time
+}
+
+var (
+	errDuplicateDelivery = errors.New("the delivery was already received")
+	errQueueFull         = errors.New("too many events are waiting to be processed")
+	errShuttingDown      = errors.New("the server is shutting down")
+)
+
+// newWebhookServer returns a server of webhooks signed with the secret, which processes events with the workers until
+// it's shut down.  The logger of the context is used to process events.
+func newWebhookServer(ctx context.Context, secret []byte, workers int) *webhookServer {
+	ctx, cancel := context.WithCancel(log.IntoContext(context.Background(), log.FromContext(ctx)))
+	s := &webhookServer{
+		secret:       secret,
+		ctx:          ctx,
+		cancel:       cancel,
+		deliveries:   make(map[string]struct{}),
+		deliveryRing: make([]string, maxDeliveries),
+		clients:      make(map[string]*webhookClient),
+	}
+	size := maxQueuedEvents / workers
+	if size < 1 {
+		size = 1
+	}
+	for i := 0; i < workers; i++ {
+		events := make(chan *webhookEvent, size)
+		s.events = append(s.events, events)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for e := range events {
+				s.process(e)
+			}
+		}()
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	delivery := github.DeliveryID(r)
+	eventType := github.WebHookType(r)
+	logger := log.FromContext(s.ctx).WithValues("delivery", delivery, "event", eventType)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "unable to read a payload", http.StatusBadRequest)
+		return
+	}
+	// Only SHA-256 signatures are accepted, since X-Hub-Signature with SHA-1 is deprecated.
+	signature := r.Header.Get(headerSignature)
+	if !strings.HasPrefix(signature, signaturePrefix) || github.ValidateSignature(signature, payload, s.secret) != nil {
+		logger.Info("Rejected a webhook with an invalid signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch eventType {
+	case eventPing:
+		fmt.Fprintln(w, "pong")
+		return
+	case eventPullRequest:
+	default:
+		logger.Info("Ignored an unsupported event")
+		fmt.Fprintln(w, "ignored")
+		return
+	}
+
+	var event github.PullRequestEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "unable to unmarshal a payload to JSON", http.StatusBadRequest)
+		return
+	}
+	if _, ok := pullRequestActions[event.GetAction()]; !ok || event.GetPullRequest().GetState() == "closed" {
+		logger.Info("Ignored a pull request event", "action", event.GetAction())
+		fmt.Fprintln(w, "ignored")
+		return
+	}
+
+	switch err := s.enqueue(&webhookEvent{delivery: delivery, event: &event}); {
+	case errors.Is(err, errDuplicateDelivery):
+		logger.Info("Ignored a webhook delivered again")
+		fmt.Fprintln(w, "duplicate")
+	case errors.Is(err, errQueueFull):
+		logger.Info("Rejected a webhook since too many events are waiting to be processed")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, errShuttingDown):
+		logger.Info("Rejected a webhook since the server is shutting down")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "accepted")
+	}
+}
+
+// enqueue queues the event to be processed by the worker of its pull request unless its delivery was already received,
+// the queue is full or the server is shutting down.  Deliveries of rejected events are not remembered so that they can
+// be redelivered.
+func (s *webhookServer) enqueue(e *webhookEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errShuttingDown
+	}
+	if _, ok := s.deliveries[e.delivery]; ok {
+		return errDuplicateDelivery
+	}
+	select {
+	case s.events[s.worker(e)] <- e:
+	default:
+		return errQueueFull
+	}
+	if e.delivery != "" {
+		if old := s.deliveryRing[s.next]; old != "" {
+			delete(s.deliveries, old)
+		}
+		s.deliveries[e.delivery] = struct{}{}
+		s.deliveryRing[s.next] = e.delivery
+		s.next = (s.next + 1) % len(s.deliveryRing)
+	}
+	return nil
+}
+
+// worker returns an index of the worker which processes events of the pull request of the event.
+func (s *webhookServer) worker(e *webhookEvent) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%s#%d",
+		e.event.GetRepo().GetOwner().GetLogin(),
+		e.event.GetRepo().GetName(),
+		e.event.GetPullRequest().GetNumber(),
+	)
+	return int(h.Sum32() % uint32(len(s.events)))
+}
+
+// Shutdown stops receiving events and waits for queued ones to be processed.  If the context is done before that,
+// events being processed are canceled and the error of the context is returned.  Webhooks received after Shutdown, e.g.
+// by handlers which an HTTP server failed to wait for, are rejected with 503 Service Unavailable.
+func (s *webhookServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		for _, events := range s.events {
+			close(events)
+		}
+	}
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	defer s.cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// process computes a size of the pull request of the event and updates the pull request.  Errors are only logged since
+// the webhook was already responded.
+func (s *webhookServer) process(e *webhookEvent) {
+	owner := e.event.GetRepo().GetOwner().GetLogin()
+	repo := e.event.GetRepo().GetName()
+	pr := e.event.GetPullRequest()
+	logger := log.FromContext(s.ctx).WithValues(
+		"delivery", e.delivery,
+		"owner", owner,
+		"repo", repo,
+		"number", pr.GetNumber(),
+	)
+	ctx, cancel := context.WithTimeout(log.IntoContext(s.ctx, logger), eventTimeout)
+	defer cancel()
+
+	if err := s.processPullRequest(ctx, e.event.GetInstallation().GetID(), owner, repo, pr); err != nil {
+		logger.Error(err, "Failed to process a pull request event")
+	}
+}
+
+// processPullRequest computes a size of the pull request and updates the pull request in the same way as the action
+// does on pull_request events.  installationID is an ID of the installation of a GitHub App which the event was
+// delivered to, or zero if it wasn't delivered to a GitHub App.
+func (s *webhookServer) processPullRequest(
+	ctx context.Context,
+	installationID int64,
+	owner, repo string,
+	pr *github.PullRequest,
+) error {
+	logger := log.FromContext(ctx)
+
+	client, author, err := s.client(installationID, owner, repo)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(ctx, client, owner, repo, pr.GetBase().GetRef())
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+	sizer, err := cfg.Sizer()
+	if err != nil {
+		return fmt.Errorf("unable to load a configuration: %w", err)
+	}
+
+	m, err := measurePullRequest(ctx, client, cfg, sizer, owner, repo, pr, trustedAttributesRef(pr), false)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		logger.Info("Skipped updating the pull request since dry-run is enabled", "size", m.size.String())
+	} else {
+		if cfg.Labels.Sync {
+			if _, err := gh.SyncLabels(ctx, client, owner, repo, sizer.Tiers()); err != nil {
+				return fmt.Errorf("unable to sync labels: %w", err)
+			}
+		}
+		if err := applyMeasurement(ctx, client, author, cfg, sizer, owner, repo, pr, m); err != nil {
+			return err
+		}
+	}
+
+	if m.verdict.Result != policy.Pass {
+		logger.Info("The pull request violates the size policy",
+			"result", m.verdict.Result.String(),
+			"message", m.verdict.Message,
+		)
+	}
+	return nil
+}
+
+// client returns a client of GitHub API for the repository, and a login which the client comments as.  A client is
+// shared by events delivered to the same installation of a GitHub App, since its tokens are valid for all repositories
+// of the installation, or by events of the same repository otherwise.  Clients which are not used for clientIdleTimeout
+// are evicted, and so are the least recently used ones beyond maxClients unless they may be used by events being
+// processed.
+func (s *webhookServer) client(installationID int64, owner, repo string) (*github.Client, *author, error) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	now := time.Now()
+	for key, c := range s.clients {
+		if now.Sub(c.lastUsed) >= clientIdleTimeout {
+			delete(s.clients, key)
+		}
+	}
+
+	key := "repository/" + owner + "/" + repo
+	if installationID != 0 {
+		key = "installation/" + strconv.FormatInt(installationID, 10)
+	}
+	if c, ok := s.clients[key]; ok {
+		c.lastUsed = now
+		return c.client, c.author, nil
+	}
+
+	if s.cache == nil {
+		cache, err := gh.NewCache(cacheDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to cache responses of GitHub API: %w", err)
+		}
+		s.cache = cache
+	}
+	client, author, err := newCachedClient(s.ctx, owner, repo, s.cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	for len(s.clients) >= maxClients {
+		var oldest string
+		for key, c := range s.clients {
+			if oldest == "" || c.lastUsed.Before(s.clients[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		if now.Sub(s.clients[oldest].lastUsed) < eventTimeout {
+			break
+		}
+		delete(s.clients, oldest)
+	}
+	s.clients[key] = &webhookClient{client: client, author: author, lastUsed: now}
+	return client, author, nil
+}
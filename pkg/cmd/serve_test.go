@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookServer(t *testing.T) {
+	const secret = "secret"
+
+	var sign = func(payload []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	var newPayload = func(t *testing.T, action string) []byte {
+		data, err := json.Marshal(&github.PullRequestEvent{
+			Action: github.String(action),
+			Repo: &github.Repository{
+				Owner: &github.User{Login: github.String("kkohtaka")},
+				Name:  github.String("gh-actions-pr-size"),
+			},
+			PullRequest: &github.PullRequest{
+				Number: github.Int(42),
+				State:  github.String("open"),
+				Base:   &github.PullRequestBranch{Ref: github.String("master")},
+				Head:   &github.PullRequestBranch{SHA: github.String("abc")},
+			},
+		})
+		require.NoError(t, err)
+		return data
+	}
+	var setup = func(t *testing.T) (*webhookServer, *[]string) {
+		configFile = ""
+		thresholds = nil
+		labelPrefix = ""
+		labelTemplate = ""
+		token = ""
+		dryRun = false
+		comment = false
+		maxSize = ""
+		checkRun = false
+		commitStatus = false
+		syncLabels = false
+		httpmock.Activate()
+		t.Cleanup(httpmock.DeactivateAndReset)
+
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/contents/.github/pr-size.yaml",
+			httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/abc",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+			httpmock.NewJsonResponderOrPanic(200, []github.CommitFile{
+				{Additions: github.Int(100), Deletions: github.Int(200)},
+			}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+			httpmock.NewJsonResponderOrPanic(200, []github.Label{}),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+			httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{}),
+		)
+		var mu sync.Mutex
+		var got []string
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+			func(req *http.Request) (*http.Response, error) {
+				var labels []string
+				if err := json.NewDecoder(req.Body).Decode(&labels); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, labels...)
+				return httpmock.NewJsonResponse(200, []github.Label{})
+			},
+		)
+
+		s := newWebhookServer(context.Background(), []byte(secret), 2)
+		t.Cleanup(func() {
+			_ = s.Shutdown(context.Background())
+		})
+		return s, &got
+	}
+	var deliver = func(s *webhookServer, event, delivery string, payload []byte, signature string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(payload)))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature-256", signature)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	t.Run("A pull request is labeled with its size.", func(t *testing.T) {
+		s, got := setup(t)
+		payload := newPayload(t, "opened")
+		resp := deliver(s, "pull_request", "1", payload, sign(payload))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		require.NoError(t, s.Shutdown(context.Background()))
+		assert.Equal(t, []string{"size/L"}, *got)
+	})
+
+	t.Run("A delivery is processed only once.", func(t *testing.T) {
+		s, got := setup(t)
+		payload := newPayload(t, "synchronize")
+		assert.Equal(t, http.StatusAccepted, deliver(s, "pull_request", "1", payload, sign(payload)).StatusCode)
+		assert.Equal(t, http.StatusOK, deliver(s, "pull_request", "1", payload, sign(payload)).StatusCode)
+		assert.Equal(t, http.StatusAccepted, deliver(s, "pull_request", "2", payload, sign(payload)).StatusCode)
+		require.NoError(t, s.Shutdown(context.Background()))
+		assert.Equal(t, []string{"size/L", "size/L"}, *got)
+	})
+
+	t.Run("Attributes of a pull request from a fork are read from the base branch.", func(t *testing.T) {
+		s, got := setup(t)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/abc",
+			httpmock.NewErrorResponder(errors.New("attributes of a fork must not be read")),
+		)
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/git/trees/def",
+			httpmock.NewJsonResponderOrPanic(200, &github.Tree{}),
+		)
+		var event github.PullRequestEvent
+		require.NoError(t, json.Unmarshal(newPayload(t, "opened"), &event))
+		event.PullRequest.Base.SHA = github.String("def")
+		event.PullRequest.Base.Repo = &github.Repository{ID: github.Int64(1)}
+		event.PullRequest.Head.Repo = &github.Repository{ID: github.Int64(2)}
+		payload, err := json.Marshal(&event)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusAccepted, deliver(s, "pull_request", "1", payload, sign(payload)).StatusCode)
+		require.NoError(t, s.Shutdown(context.Background()))
+		assert.Equal(t, []string{"size/L"}, *got)
+	})
+
+	t.Run("Events of a pull request are processed in the order received.", func(t *testing.T) {
+		s, _ := setup(t)
+		var mu sync.Mutex
+		var heads []string
+		httpmock.RegisterResponder(
+			"GET",
+			`=~^https://api\.github\.com/repos/kkohtaka/gh-actions-pr-size/git/trees/(\w+)\z`,
+			func(req *http.Request) (*http.Response, error) {
+				head := httpmock.MustGetSubmatch(req, 1)
+				if head == "head0" {
+					// A slow event must not be overtaken by the following ones.
+					time.Sleep(50 * time.Millisecond)
+				}
+				mu.Lock()
+				heads = append(heads, head)
+				mu.Unlock()
+				return httpmock.NewJsonResponse(200, &github.Tree{})
+			},
+		)
+		var want []string
+		for i := 0; i < 10; i++ {
+			head := "head" + strconv.Itoa(i)
+			var event github.PullRequestEvent
+			require.NoError(t, json.Unmarshal(newPayload(t, "synchronize"), &event))
+			event.PullRequest.Head.SHA = github.String(head)
+			payload, err := json.Marshal(&event)
+			require.NoError(t, err)
+			resp := deliver(s, "pull_request", strconv.Itoa(i), payload, sign(payload))
+			require.Equal(t, http.StatusAccepted, resp.StatusCode)
+			want = append(want, head)
+		}
+		require.NoError(t, s.Shutdown(context.Background()))
+		assert.Equal(t, want, heads)
+	})
+
+	t.Run("Clients are shared by installations and evicted when they're idle.", func(t *testing.T) {
+		s, _ := setup(t)
+		var get = func(installationID int64, repo string) *github.Client {
+			c, _, err := s.client(installationID, "kkohtaka", repo)
+			require.NoError(t, err)
+			return c
+		}
+		installation := get(1, "a")
+		assert.Same(t, installation, get(1, "b"))
+		assert.NotSame(t, installation, get(2, "a"))
+		repository := get(0, "a")
+		assert.Same(t, repository, get(0, "a"))
+		assert.NotSame(t, repository, get(0, "b"))
+		assert.Len(t, s.clients, 4)
+
+		s.clients["installation/1"].lastUsed = time.Now().Add(-clientIdleTimeout)
+		assert.NotSame(t, installation, get(1, "a"))
+		assert.Len(t, s.clients, 4)
+
+		for i := int64(100); len(s.clients) < maxClients; i++ {
+			get(i, "a")
+		}
+		// Clients used by events being processed aren't evicted.
+		get(1000, "a")
+		assert.Len(t, s.clients, maxClients+1)
+
+		for _, c := range s.clients {
+			c.lastUsed = time.Now().Add(-eventTimeout)
+		}
+		s.clients["installation/2"].lastUsed = time.Now().Add(-2 * eventTimeout)
+		get(1001, "a")
+		assert.Len(t, s.clients, maxClients)
+		assert.NotContains(t, s.clients, "installation/2")
+		assert.Contains(t, s.clients, "installation/1001")
+	})
+
+	t.Run("Webhooks are rejected after a shutdown.", func(t *testing.T) {
+		s, got := setup(t)
+		require.NoError(t, s.Shutdown(context.Background()))
+		payload := newPayload(t, "opened")
+		resp := deliver(s, "pull_request", "1", payload, sign(payload))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Empty(t, *got)
+	})
+
+	t.Run("Webhooks without valid signatures are rejected.", func(t *testing.T) {
+		s, got := setup(t)
+		payload := newPayload(t, "opened")
+
+		mac := hmac.New(sha1.New, []byte(secret))
+		mac.Write(payload)
+		for _, signature := range []string{
+			"",
+			"sha256=" + strings.Repeat("0", 64),
+			sign([]byte("{}")),
+			"sha1=" + hex.EncodeToString(mac.Sum(nil)),
+		} {
+			resp := deliver(s, "pull_request", "1", payload, signature)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, signature)
+		}
+		require.NoError(t, s.Shutdown(context.Background()))
+		assert.Empty(t, *got)
+	})
+
+	t.Run("Other events and actions are ignored.", func(t *testing.T) {
+		s, got := setup(t)
+		payload := newPayload(t, "closed")
+		assert.Equal(t, http.StatusOK, deliver(s, "pull_request", "1", payload, sign(payload)).StatusCode)
+		payload = []byte(`{"zen": "Keep it logically awesome."}`)
+		assert.Equal(t, http.StatusOK, deliver(s, "ping", "2", payload, sign(payload)).StatusCode)
+		payload = []byte(`{"action": "created"}`)
+		assert.Equal(t, http.StatusOK, deliver(s, "issue_comment", "3", payload, sign(payload)).StatusCode)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+		require.NoError(t, s.Shutdown(context.Background()))
+		assert.Empty(t, *got)
+	})
+}
+
+func TestServeFlags(t *testing.T) {
+	args := []string{
+		"--comment",
+		"--check-run",
+		"--commit-status",
+		"--max-size=M",
+		"--bypass-label=skip-size",
+		"--bypass-keyword=[skip size]",
+		"--include=**/*.go",
+		"--exclude=**/go.sum",
+		"--sync-labels",
+	}
+	t.Cleanup(func() {
+		comment, checkRun, commitStatus, syncLabels = false, false, false, false
+		maxSize, bypassLabel, bypassKeyword = "", "", ""
+		includePatterns, excludePatterns = nil, nil
+		serveCmd.Flags().VisitAll(func(f *pflag.Flag) {
+			f.Changed = false
+		})
+	})
+
+	// The server reports sizes in the same way as the action, so it accepts the same flags.
+	require.NoError(t, serveCmd.ParseFlags(args))
+	assert.True(t, comment)
+	assert.True(t, checkRun)
+	assert.True(t, commitStatus)
+	assert.True(t, syncLabels)
+	assert.Equal(t, "M", maxSize)
+	assert.Equal(t, "skip-size", bypassLabel)
+	assert.Equal(t, "[skip size]", bypassKeyword)
+	assert.Equal(t, []string{"**/*.go"}, includePatterns)
+	assert.Equal(t, []string{"**/go.sum"}, excludePatterns)
+}
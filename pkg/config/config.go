@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is a path of a configuration file in a repository.
+const DefaultPath = ".github/pr-size.yaml"
+
+const (
+	// DefaultLabelPrefix is a prefix of labels of tiers which have no explicit labels.
+	DefaultLabelPrefix = "size/"
+	// DefaultFromColor is a default color of the label of the smallest tier.
+	DefaultFromColor = "009900"
+	// DefaultToColor is a default color of the label of the largest tier.
+	DefaultToColor = "ee0000"
+)
+
+// Config is a configuration of the action, which is usually stored in a repository.
+type Config struct {
+	// Tiers is an ordered list of tiers of pull request size.
+	Tiers []Tier `yaml:"tiers"`
+	// LabelPrefix is a prefix of labels of tiers which have no explicit labels.  It defaults to "size/".
+	LabelPrefix string `yaml:"labelPrefix,omitempty"`
+	// LabelTemplate is a Go template of labels of tiers which have no explicit labels (e.g. "pr-size: {{.Name}}").
+	// The name of a tier is available as {{.Name}}.  It takes precedence over LabelPrefix if it's specified.
+	LabelTemplate string `yaml:"labelTemplate,omitempty"`
+	// Include is a list of glob patterns of files counted in the size.  If it's empty, all files are counted.
+	Include []string `yaml:"include,omitempty"`
+	// Exclude is a list of glob patterns of files not counted in the size (e.g. "**/go.sum").
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Comment is a configuration of a comment posted on pull requests.
+	Comment Comment `yaml:"comment,omitempty"`
+	// Policy is a rule which limits the size of pull requests.
+	Policy policy.Policy `yaml:"policy,omitempty"`
+	// Labels is a configuration of labels of tiers in a repository.
+	Labels Labels `yaml:"labels,omitempty"`
+}
+
+// Labels is a configuration of labels of tiers in a repository.
+type Labels struct {
+	// Sync creates labels of tiers in a repository and updates their colors and descriptions before labeling.
+	Sync bool `yaml:"sync,omitempty"`
+	// FromColor is a color of the label of the smallest tier.  Colors of the other labels are a gradient from
+	// FromColor to ToColor.
+	FromColor string `yaml:"fromColor,omitempty"`
+	// ToColor is a color of the label of the largest tier.
+	ToColor string `yaml:"toColor,omitempty"`
+}
+
+// Comment is a configuration of a comment which shows a breakdown of the size on pull requests.
+type Comment struct {
+	// Enabled enables the comment.
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Threshold is a name of a tier.  Pull requests in the tier or larger ones get a hint to split them.
+	Threshold string `yaml:"threshold,omitempty"`
+	// DeleteBelowThreshold deletes the comment from pull requests smaller than the threshold, instead of keeping it.
+	DeleteBelowThreshold bool `yaml:"deleteBelowThreshold,omitempty"`
+}
+
+// Tier is a configuration of a tier of pull request size.
+type Tier struct {
+	// Name is a short name of the tier (e.g. "XL").
+	Name string `yaml:"name"`
+	// Max is an inclusive upper bound of the number of changed lines.  It must be omitted on the last tier.
+	Max *int `yaml:"max,omitempty"`
+	// Label is a name of a label attached to pull requests in the tier.  It defaults to the name with the label
+	// prefix.
+	Label string `yaml:"label,omitempty"`
+	// Color is a hexadecimal color code of the label (e.g. "ee0701").  It defaults to a gradient of label colors.
+	Color string `yaml:"color,omitempty"`
+	// Description is a description of the label.  It defaults to the range of changed lines (e.g. "100-499 changed
+	// lines").
+	Description string `yaml:"description,omitempty"`
+}
+
+// Default returns a configuration which is used when no configuration file exists.
+func Default() *Config {
+	c := &Config{
+		LabelPrefix: DefaultLabelPrefix,
+		Labels: Labels{
+			FromColor: DefaultFromColor,
+			ToColor:   DefaultToColor,
+		},
+	}
+	for _, tier := range gh.DefaultTiers {
+		t := Tier{Name: tier.Name}
+		if tier.Max != gh.Unbounded {
+			max := tier.Max
+			t.Max = &max
+		}
+		c.Tiers = append(c.Tiers, t)
+	}
+	return c
+}
+
+// Parse parses a YAML document as a configuration and validates it.  Omitted fields are filled with default values.
+func Parse(data []byte) (*Config, error) {
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode YAML: %w", err)
+	}
+	if len(c.Tiers) == 0 {
+		c.Tiers = Default().Tiers
+	}
+	if c.LabelPrefix == "" {
+		c.LabelPrefix = DefaultLabelPrefix
+	}
+	if c.Labels.FromColor == "" {
+		c.Labels.FromColor = DefaultFromColor
+	}
+	if c.Labels.ToColor == "" {
+		c.Labels.ToColor = DefaultToColor
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c *Config) Validate() error {
+	sizer, err := c.Sizer()
+	if err != nil {
+		return err
+	}
+	if c.Comment.Threshold != "" && sizer.Index(c.Comment.Threshold) < 0 {
+		return fmt.Errorf("invalid comment: threshold %q is not a tier", c.Comment.Threshold)
+	}
+	if c.Comment.DeleteBelowThreshold && c.Comment.Threshold == "" {
+		return errors.New("invalid comment: deleteBelowThreshold requires threshold")
+	}
+	if err := c.Policy.Validate(sizer); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	if _, err := c.GlobFilter(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Load reads a configuration file at the path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read a configuration file: %w", err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse a configuration file at %q: %w", path, err)
+	}
+	return c, nil
+}
+
+// Sizer returns a sizer which classifies pull requests into the configured tiers.
+func (c *Config) Sizer() (*gh.Sizer, error) {
+	from, err := parseColor(c.Labels.FromColor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labels: %w", err)
+	}
+	to, err := parseColor(c.Labels.ToColor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labels: %w", err)
+	}
+
+	label, err := c.labelFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	tiers := make([]gh.Tier, 0, len(c.Tiers))
+	for i, tier := range c.Tiers {
+		t := gh.Tier{
+			Name:        tier.Name,
+			Max:         gh.Unbounded,
+			Label:       tier.Label,
+			Color:       strings.ToLower(strings.TrimPrefix(tier.Color, "#")),
+			Description: tier.Description,
+		}
+		if t.Label == "" {
+			if t.Label, err = label(tier.Name); err != nil {
+				return nil, err
+			}
+		}
+		if tier.Max != nil {
+			t.Max = *tier.Max
+		} else if i < len(c.Tiers)-1 {
+			return nil, fmt.Errorf("invalid tiers: tier %q must have an upper bound", tier.Name)
+		}
+		if t.Color == "" {
+			t.Color = gradient(from, to, i, len(c.Tiers))
+		} else if _, err := parseColor(tier.Color); err != nil {
+			return nil, fmt.Errorf("invalid tiers: tier %q: %w", tier.Name, err)
+		}
+		if t.Description == "" {
+			min := 0
+			if i > 0 {
+				min = tiers[i-1].Max + 1
+			}
+			if t.Max == gh.Unbounded {
+				t.Description = fmt.Sprintf("%d or more changed lines", min)
+			} else {
+				t.Description = fmt.Sprintf("%d-%d changed lines", min, t.Max)
+			}
+		}
+		tiers = append(tiers, t)
+	}
+	sizer, err := gh.NewSizer(tiers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tiers: %w", err)
+	}
+	return sizer, nil
+}
+
+// labelFunc returns a function which renders a label of a tier by the label template or the label prefix.
+func (c *Config) labelFunc() (func(name string) (string, error), error) {
+	if c.LabelTemplate == "" {
+		return func(name string) (string, error) {
+			return c.LabelPrefix + name, nil
+		}, nil
+	}
+	tmpl, err := template.New("label").Parse(c.LabelTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labelTemplate: %w", err)
+	}
+	return func(name string) (string, error) {
+		var b strings.Builder
+		data := struct{ Name string }{Name: name}
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("invalid labelTemplate: render a label of tier %q: %w", name, err)
+		}
+		return b.String(), nil
+	}, nil
+}
+
+// parseColor parses a hexadecimal color code, which may be prefixed with "#", into RGB components.
+func parseColor(s string) ([3]uint8, error) {
+	var rgb [3]uint8
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || len(b) != 3 {
+		return rgb, fmt.Errorf("invalid color %q: a hexadecimal color code like \"ee0701\" is required", s)
+	}
+	copy(rgb[:], b)
+	return rgb, nil
+}
+
+// gradient returns a color code at the i-th of n steps of a linear gradient from one color to another.
+func gradient(from, to [3]uint8, i, n int) string {
+	var rgb [3]uint8
+	for k := range rgb {
+		v := int(from[k])
+		if n > 1 {
+			v += (int(to[k]) - int(from[k])) * i / (n - 1)
+		}
+		rgb[k] = uint8(v)
+	}
+	return hex.EncodeToString(rgb[:])
+}
+
+// SetThresholds overrides upper bounds of the tiers except the last one, which is unbounded.
+func (c *Config) SetThresholds(thresholds []int) error {
+	if len(thresholds) != len(c.Tiers)-1 {
+		return fmt.Errorf(
+			"%d thresholds are required for %d tiers, but %d are specified",
+			len(c.Tiers)-1, len(c.Tiers), len(thresholds),
+		)
+	}
+	for i := range thresholds {
+		max := thresholds[i]
+		c.Tiers[i].Max = &max
+	}
+	if _, err := c.Sizer(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GlobFilter returns a filter which excludes files by the configured glob patterns.
+func (c *Config) GlobFilter() (*gh.GlobFilter, error) {
+	f, err := gh.NewGlobFilter(c.Include, c.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file patterns: %w", err)
+	}
+	return f, nil
+}
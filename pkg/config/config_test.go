@@ -0,0 +1,242 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/config"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		name      string
+		data      string
+		wantTiers []gh.Tier
+	}{
+		{
+			name: "An empty document results in the default tiers.",
+			data: "",
+			wantTiers: []gh.Tier{
+				{Name: "XS", Max: 9, Label: "size/XS", Color: "009900", Description: "0-9 changed lines"},
+				{Name: "S", Max: 29, Label: "size/S", Color: "2f7b00", Description: "10-29 changed lines"},
+				{Name: "M", Max: 99, Label: "size/M", Color: "5f5c00", Description: "30-99 changed lines"},
+				{Name: "L", Max: 499, Label: "size/L", Color: "8e3e00", Description: "100-499 changed lines"},
+				{Name: "XL", Max: 999, Label: "size/XL", Color: "be1f00", Description: "500-999 changed lines"},
+				{Name: "XXL", Max: gh.Unbounded, Label: "size/XXL", Color: "ee0000", Description: "1000 or more changed lines"},
+			},
+		},
+		{
+			name: "Labels default to the name with the size prefix.",
+			data: `
+tiers:
+- name: small
+  max: 99
+- name: large
+  label: big
+`,
+			wantTiers: []gh.Tier{
+				{Name: "small", Max: 99, Label: "size/small", Color: "009900", Description: "0-99 changed lines"},
+				{Name: "large", Max: gh.Unbounded, Label: "big", Color: "ee0000", Description: "100 or more changed lines"},
+			},
+		},
+		{
+			name: "Colors and descriptions of labels are configured.",
+			data: `
+labels:
+  fromColor: "#000000"
+  toColor: "#FFFFFF"
+tiers:
+- name: small
+  max: 9
+- name: medium
+  max: 99
+  color: "#ABCDEF"
+- name: large
+  description: Too large
+`,
+			wantTiers: []gh.Tier{
+				{Name: "small", Max: 9, Label: "size/small", Color: "000000", Description: "0-9 changed lines"},
+				{Name: "medium", Max: 99, Label: "size/medium", Color: "abcdef", Description: "10-99 changed lines"},
+				{Name: "large", Max: gh.Unbounded, Label: "size/large", Color: "ffffff", Description: "Too large"},
+			},
+		},
+		{
+			name: "Labels are rendered from a template.",
+			data: `
+labelPrefix: "ignored/"
+labelTemplate: "pr-size: {{.Name}}"
+tiers:
+- name: small
+  max: 99
+- name: large
+  label: big
+`,
+			wantTiers: []gh.Tier{
+				{Name: "small", Max: 99, Label: "pr-size: small", Color: "009900", Description: "0-99 changed lines"},
+				{Name: "large", Max: gh.Unbounded, Label: "big", Color: "ee0000", Description: "100 or more changed lines"},
+			},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := config.Parse([]byte(tt.data))
+			require.NoError(t, err)
+			sizer, err := c.Sizer()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTiers, sizer.Tiers())
+		})
+	}
+}
+
+func TestParseReturnsError(t *testing.T) {
+	tcs := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "The document is not YAML.",
+			data:    "tiers: [",
+			wantErr: "decode YAML: ",
+		},
+		{
+			name:    "The document has an unknown field.",
+			data:    "foo: bar",
+			wantErr: "decode YAML: ",
+		},
+		{
+			name: "A tier in the middle has no upper bound.",
+			data: `
+tiers:
+- name: small
+- name: large
+`,
+			wantErr: `tier "small" must have an upper bound`,
+		},
+		{
+			name: "The last tier has an upper bound.",
+			data: `
+tiers:
+- name: small
+  max: 10
+- name: large
+  max: 100
+`,
+			wantErr: `the last tier "large" must not have an upper bound`,
+		},
+		{
+			name: "Upper bounds are not in ascending order.",
+			data: `
+tiers:
+- name: small
+  max: 10
+- name: medium
+  max: 10
+- name: large
+`,
+			wantErr: `tier "medium" must have a larger upper bound than tier "small"`,
+		},
+		{
+			name:    "An exclude pattern is malformed.",
+			data:    "exclude: ['[a']",
+			wantErr: "invalid file patterns: ",
+		},
+		{
+			name:    "A threshold of the comment is not a tier.",
+			data:    "comment: {enabled: true, threshold: XXXL}",
+			wantErr: `invalid comment: threshold "XXXL" is not a tier`,
+		},
+		{
+			name:    "The comment is deleted below a threshold without the threshold.",
+			data:    "comment: {enabled: true, deleteBelowThreshold: true}",
+			wantErr: "invalid comment: deleteBelowThreshold requires threshold",
+		},
+		{
+			name:    "A maximum size of the policy is not a tier.",
+			data:    "policy: {maxSize: XXXL}",
+			wantErr: `invalid policy: max size "XXXL" is not a tier`,
+		},
+		{
+			name:    "A color of labels is invalid.",
+			data:    "labels: {fromColor: green}",
+			wantErr: `invalid labels: invalid color "green"`,
+		},
+		{
+			name: "A color of a tier is invalid.",
+			data: `
+tiers:
+- name: small
+  max: 10
+  color: "#fff"
+- name: large
+`,
+			wantErr: `invalid tiers: tier "small": invalid color "#fff"`,
+		},
+		{
+			name: "Tier names are duplicated.",
+			data: `
+tiers:
+- name: small
+  max: 10
+- name: small
+`,
+			wantErr: `tier name "small" is duplicated`,
+		},
+		{
+			name:    "A label template is malformed.",
+			data:    `labelTemplate: "size/{{.Name"`,
+			wantErr: `invalid labelTemplate: template: label:1: unclosed action`,
+		},
+		{
+			name:    "A label template refers to an unknown field.",
+			data:    `labelTemplate: "size/{{.Label}}"`,
+			wantErr: `invalid labelTemplate: render a label of tier "XS": `,
+		},
+		{
+			name:    "A label template renders the same label for all tiers.",
+			data:    `labelTemplate: "size"`,
+			wantErr: `tier label "size" is duplicated`,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Parse([]byte(tt.data))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseGlobFilter(t *testing.T) {
+	c, err := config.Parse([]byte(`
+include:
+- "**"
+exclude:
+- "**/go.sum"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"**"}, c.Include)
+	assert.Equal(t, []string{"**/go.sum"}, c.Exclude)
+
+	f, err := c.GlobFilter()
+	require.NoError(t, err)
+	reason, err := f.Exclude(context.Background(), "tools/go.sum")
+	require.NoError(t, err)
+	assert.Equal(t, `excluded by "**/go.sum"`, reason)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pr-size.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("tiers:\n- name: small\n  max: 1\n- name: large\n"), 0o644))
+
+	c, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Len(t, c.Tiers, 2)
+
+	_, err = config.Load(filepath.Join(t.TempDir(), "not-exist.yaml"))
+	assert.ErrorContains(t, err, "read a configuration file: ")
+}
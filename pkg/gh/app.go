@@ -0,0 +1,148 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"golang.org/x/oauth2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// jwtLifetime is a lifetime of JSON Web Tokens of a GitHub App, which GitHub limits to 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew is how far back issued times of JSON Web Tokens are dated to allow clock drift from GitHub.
+	jwtClockSkew = time.Minute
+)
+
+// AppJWTSource is a token source of JSON Web Tokens by which a GitHub App authenticates itself.  The tokens are signed
+// with RS256 by the private key of the app.
+type AppJWTSource struct {
+	appID int64
+	key   *rsa.PrivateKey
+}
+
+var _ oauth2.TokenSource = &AppJWTSource{}
+
+// NewAppJWTSource returns a token source of JSON Web Tokens of the app.  The private key must be an RSA key encoded in
+// PEM, either in PKCS #1 as GitHub generates or in PKCS #8.
+func NewAppJWTSource(appID int64, privateKey []byte) (*AppJWTSource, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("parse a private key: no PEM block is found")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		k, e := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if e != nil {
+			return nil, fmt.Errorf("parse a private key: %w", err)
+		}
+		var ok bool
+		if key, ok = k.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("parse a private key: %T is not an RSA private key", k)
+		}
+	}
+	return &AppJWTSource{appID: appID, key: key}, nil
+}
+
+// Token implements oauth2.TokenSource.
+func (s *AppJWTSource) Token() (*oauth2.Token, error) {
+	now := time.Now()
+	expiry := now.Add(jwtLifetime)
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return nil, fmt.Errorf("encode a header of a JWT: %w", err)
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": expiry.Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode claims of a JWT: %w", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign a JWT: %w", err)
+	}
+	return &oauth2.Token{
+		AccessToken: unsigned + "." + base64.RawURLEncoding.EncodeToString(signature),
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+// InstallationTokenSource is a token source of installation access tokens of a GitHub App for a repository.  Wrap it
+// by oauth2.ReuseTokenSource (e.g. with oauth2.NewClient) so that a token is reused until it expires and refreshed
+// transparently afterwards.
+type InstallationTokenSource struct {
+	ctx         context.Context
+	client      *github.Client
+	owner, repo string
+
+	// installationID is an ID of the installation of the app on the repository, which is looked up on the first token.
+	installationID int64
+}
+
+var _ oauth2.TokenSource = &InstallationTokenSource{}
+
+// NewInstallationTokenSource returns a token source of installation access tokens for the repository.  The client must
+// be authenticated as the app, e.g. by AppJWTSource.  The context is used to request tokens.
+func NewInstallationTokenSource(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+) *InstallationTokenSource {
+	return &InstallationTokenSource{
+		ctx:    ctx,
+		client: client,
+		owner:  owner,
+		repo:   repo,
+	}
+}
+
+// Token implements oauth2.TokenSource.
+func (s *InstallationTokenSource) Token() (*oauth2.Token, error) {
+	logger := log.FromContext(s.ctx).WithValues(
+		"owner", s.owner,
+		"repo", s.repo,
+	)
+
+	if s.installationID == 0 {
+		installation, _, err := s.client.Apps.FindRepositoryInstallation(s.ctx, s.owner, s.repo)
+		if err != nil {
+			logger.Error(err, "Failed to find an installation of a GitHub App on a repository")
+			return nil, fmt.Errorf("find an installation on %s/%s: %w", s.owner, s.repo, err)
+		}
+		s.installationID = installation.GetID()
+	}
+	logger = logger.WithValues("installation", s.installationID)
+
+	token, _, err := s.client.Apps.CreateInstallationToken(s.ctx, s.installationID, nil)
+	if err != nil {
+		logger.Error(err, "Failed to create an installation access token")
+		return nil, fmt.Errorf("create an installation access token: %w", err)
+	}
+	logger.Info("Created an installation access token", "expiresAt", token.GetExpiresAt())
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "token",
+		Expiry:      token.GetExpiresAt(),
+	}, nil
+}
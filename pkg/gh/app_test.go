@@ -0,0 +1,151 @@
+package gh_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestAppJWTSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	tcs := []struct {
+		name  string
+		block *pem.Block
+	}{
+		{
+			name:  "A private key is encoded in PKCS #1.",
+			block: &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		},
+		{
+			name:  "A private key is encoded in PKCS #8.",
+			block: &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := gh.NewAppJWTSource(42, pem.EncodeToMemory(tt.block))
+			require.NoError(t, err)
+			token, err := ts.Token()
+			require.NoError(t, err)
+
+			parts := strings.Split(token.AccessToken, ".")
+			require.Len(t, parts, 3)
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			require.NoError(t, err)
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+			var header map[string]string
+			data, err := base64.RawURLEncoding.DecodeString(parts[0])
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &header))
+			assert.Equal(t, map[string]string{"alg": "RS256", "typ": "JWT"}, header)
+
+			var claims struct {
+				IssuedAt  int64  `json:"iat"`
+				ExpiresAt int64  `json:"exp"`
+				Issuer    string `json:"iss"`
+			}
+			data, err = base64.RawURLEncoding.DecodeString(parts[1])
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &claims))
+			assert.Equal(t, "42", claims.Issuer)
+			assert.LessOrEqual(t, claims.IssuedAt, time.Now().Unix())
+			assert.LessOrEqual(t, claims.ExpiresAt-claims.IssuedAt, int64(10*time.Minute/time.Second))
+			assert.Equal(t, claims.ExpiresAt, token.Expiry.Unix())
+		})
+	}
+}
+
+func TestNewAppJWTSourceReturnsError(t *testing.T) {
+	_, err := gh.NewAppJWTSource(42, []byte("not a key"))
+	assert.ErrorContains(t, err, "parse a private key: no PEM block is found")
+
+	_, err = gh.NewAppJWTSource(42, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("invalid")}))
+	assert.ErrorContains(t, err, "parse a private key: ")
+}
+
+func TestInstallationTokenSource(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	const baseURL = "https://api.github.com"
+	httpmock.RegisterResponder(
+		"GET",
+		baseURL+"/repos/kkohtaka/gh-actions-pr-size/installation",
+		httpmock.NewJsonResponderOrPanic(200, &github.Installation{ID: github.Int64(7)}),
+	)
+	var issued int
+	httpmock.RegisterResponder(
+		"POST",
+		baseURL+"/app/installations/7/access_tokens",
+		func(req *http.Request) (*http.Response, error) {
+			issued++
+			// The first token has already expired so that it's refreshed on the next request.
+			expiresAt := time.Now().Add(-time.Minute)
+			if issued > 1 {
+				expiresAt = time.Now().Add(time.Hour)
+			}
+			return httpmock.NewJsonResponse(201, &github.InstallationToken{
+				Token:     github.String(strings.Repeat("t", issued)),
+				ExpiresAt: &expiresAt,
+			})
+		},
+	)
+
+	ts := oauth2.ReuseTokenSource(nil, gh.NewInstallationTokenSource(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+	))
+	for _, want := range []string{"t", "tt", "tt"} {
+		token, err := ts.Token()
+		require.NoError(t, err)
+		assert.Equal(t, want, token.AccessToken)
+	}
+	assert.Equal(t, 2, issued)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET "+baseURL+"/repos/kkohtaka/gh-actions-pr-size/installation"])
+}
+
+func TestInstallationTokenSourceReturnsError(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/installation",
+		httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+	)
+
+	_, err := gh.NewInstallationTokenSource(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+	).Token()
+	assert.ErrorContains(t, err, "find an installation on kkohtaka/gh-actions-pr-size: ")
+}
@@ -0,0 +1,358 @@
+package gh
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
+
+	// maxCacheEntries is the maximum number of responses cached in memory.  The least recently used ones are evicted.
+	maxCacheEntries = 1000
+	// maxCacheFiles is the maximum number of responses cached in a directory.  The least recently used ones are
+	// removed.
+	maxCacheFiles = 10000
+	// maxCacheAge is the duration after which a response cached in a directory is removed unless it's used again.
+	maxCacheAge = 7 * 24 * time.Hour
+	// pruneInterval is the interval at which a directory is pruned by a long-running process.
+	pruneInterval = 24 * time.Hour
+)
+
+// CacheTransport is an http.RoundTripper which caches responses of GET requests with their ETag or Last-Modified, and
+// sends conditional requests with If-None-Match or If-Modified-Since for the cached URLs.  If GitHub API responds with
+// 304 Not Modified, which doesn't count against the rate limit, the cached response is returned instead.
+//
+// Responses are cached in memory, and also in a directory if it's specified so that they're reused across processes,
+// e.g. by saving the directory with actions/cache.  Since every cached response is revalidated by GitHub API with the
+// credentials of the request, a response is never returned to a request which isn't allowed to get it.  For the same
+// reason, a Cache can be shared by transports with different credentials.
+type CacheTransport struct {
+	// Base is the underlying transport.  If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	cache *Cache
+}
+
+var _ http.RoundTripper = &CacheTransport{}
+
+// Cache is a store of responses cached by CacheTransports.  At most 1000 responses are kept in memory.  Files in the
+// directory which were not used for a week are removed, and so are the least recently used ones beyond 10000 files.
+type Cache struct {
+	// dir is a directory in which responses are cached, or empty if they're cached only in memory.
+	dir string
+
+	mu sync.Mutex
+	// prunedAt is when the directory was pruned last time, which happens before a response is written to it.
+	prunedAt time.Time
+	entries  map[string]*list.Element
+	// lru is a list of the cached responses from the most recently used one.
+	lru *list.List
+}
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	StatusCode   int         `json:"statusCode"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// cacheElement is an element of the LRU list.
+type cacheElement struct {
+	key   string
+	entry *cacheEntry
+}
+
+// NewCacheTransport returns a transport which caches responses of the base transport.  If dir is not empty, responses
+// are also cached in the directory, which is created if it doesn't exist.
+func NewCacheTransport(base http.RoundTripper, dir string) (*CacheTransport, error) {
+	cache, err := NewCache(dir)
+	if err != nil {
+		return nil, err
+	}
+	return cache.Transport(base), nil
+}
+
+// NewCache returns a store of responses.  If dir is not empty, responses are also cached in the directory, which is
+// created if it doesn't exist.
+func NewCache(dir string) (*Cache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create a cache directory: %w", err)
+		}
+	}
+	return &Cache{
+		dir:     dir,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// Transport returns a transport which caches responses of the base transport in the cache.
+func (c *Cache) Transport(base http.RoundTripper) *CacheTransport {
+	return &CacheTransport{
+		Base:  base,
+		cache: c,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base().RoundTrip(req)
+	}
+	logger := log.FromContext(req.Context()).WithValues("url", req.URL.Redacted())
+
+	key := cacheKey(req)
+	entry := t.cache.load(req, key)
+	r := req
+	if entry != nil {
+		r = req.Clone(req.Context())
+		if entry.ETag != "" {
+			r.Header.Set(headerIfNoneMatch, entry.ETag)
+		}
+		if entry.LastModified != "" {
+			r.Header.Set(headerIfModifiedSince, entry.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	validated := resp.Header.Get(headerETag) != "" || resp.Header.Get(headerLastModified) != ""
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		logger.Info("A response of GitHub API was not modified, so the cached one is used")
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		t.cache.touch(key)
+		return entry.response(req, resp), nil
+	case resp.StatusCode == http.StatusOK && validated:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		t.cache.store(req, key, &cacheEntry{
+			ETag:         resp.Header.Get(headerETag),
+			LastModified: resp.Header.Get(headerLastModified),
+			StatusCode:   resp.StatusCode,
+			Header:       resp.Header.Clone(),
+			Body:         body,
+		})
+	}
+	return resp, nil
+}
+
+func (t *CacheTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// cacheKey returns a key of a cached response of the request.  Media types are part of the key since GitHub API
+// responds differently by them.  Credentials are not, so that responses are reused after tokens are rotated.
+func cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return hex.EncodeToString(sum[:])
+}
+
+// load returns a cached response of the key from memory or the directory, or nil if it isn't cached.
+func (c *Cache) load(req *http.Request, key string) *cacheEntry {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cacheElement).entry
+	}
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.FromContext(req.Context()).Error(err, "Failed to read a cached response", "url", req.URL.Redacted())
+		}
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.FromContext(req.Context()).Error(err, "Failed to decode a cached response", "url", req.URL.Redacted())
+		return nil
+	}
+	c.remember(key, &entry)
+	return &entry
+}
+
+// store caches the response of the key in memory and the directory.  A failure to write the directory is only logged
+// since the response is still available.
+func (c *Cache) store(req *http.Request, key string, entry *cacheEntry) {
+	c.remember(key, entry)
+	if c.dir == "" {
+		return
+	}
+	if c.pruneDue() {
+		if err := c.prune(); err != nil {
+			log.FromContext(req.Context()).Error(err, "Failed to prune cached responses", "dir", c.dir)
+		}
+	}
+	if err := c.write(key, entry); err != nil {
+		log.FromContext(req.Context()).Error(err, "Failed to write a cached response", "url", req.URL.Redacted())
+	}
+}
+
+// remember caches the response of the key in memory, and evicts the least recently used one if there are too many.
+func (c *Cache) remember(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheElement).entry = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheElement{key: key, entry: entry})
+	if c.lru.Len() > maxCacheEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheElement).key)
+	}
+}
+
+// write writes the response of the key to the directory.  The file is replaced atomically so that concurrent
+// processes never read a partially written one.
+func (c *Cache) write(key string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path(key))
+}
+
+// pruneDue reports whether the directory should be pruned, and records that it's pruned now if so.
+func (c *Cache) pruneDue() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.prunedAt.IsZero() && time.Since(c.prunedAt) < pruneInterval {
+		return false
+	}
+	c.prunedAt = time.Now()
+	return true
+}
+
+// touch marks the response of the key in the directory as used, so that it isn't pruned while it's still revalidated.
+// The response may be cached only in memory, so errors are ignored.
+func (c *Cache) touch(key string) {
+	if c.dir == "" {
+		return
+	}
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+}
+
+// prune removes files in the directory which were not used for maxCacheAge, and the least recently used ones beyond
+// maxCacheFiles, so that the directory doesn't keep growing when it's saved across runs.  Temporary files left by
+// interrupted writes are removed likewise.
+func (c *Cache) prune() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		name    string
+		modTime time.Time
+	}
+	var files []file
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !(strings.HasSuffix(e.Name(), ".json") || strings.HasSuffix(e.Name(), ".tmp")) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		files = append(files, file{name: e.Name(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	expiry := time.Now().Add(-maxCacheAge)
+	for i, f := range files {
+		if i < maxCacheFiles && f.modTime.After(expiry) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, f.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// response returns the cached response to the request.  Headers of the 304 response, such as the rate limit, take
+// precedence over the cached ones except for ones describing the body.
+func (e *cacheEntry) response(req *http.Request, notModified *http.Response) *http.Response {
+	header := e.Header.Clone()
+	for k, v := range notModified.Header {
+		if strings.HasPrefix(k, "Content-") {
+			continue
+		}
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
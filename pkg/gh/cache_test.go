@@ -0,0 +1,188 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheTransport(t *testing.T) {
+	tcs := []struct {
+		name          string
+		header        string
+		value         string
+		conditional   string
+		persistent    bool
+		wantFileCount int
+	}{
+		{
+			name:        "A response has an ETag.",
+			header:      "ETag",
+			value:       `W/"abc"`,
+			conditional: "If-None-Match",
+		},
+		{
+			name:        "A response has Last-Modified.",
+			header:      "Last-Modified",
+			value:       "Mon, 02 Jan 2006 15:04:05 GMT",
+			conditional: "If-Modified-Since",
+		},
+		{
+			name:          "Responses are cached in a directory.",
+			header:        "ETag",
+			value:         `"abc"`,
+			conditional:   "If-None-Match",
+			persistent:    true,
+			wantFileCount: 1,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.NewMockTransport()
+			var conditions []string
+			mock.RegisterResponder(
+				"GET",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+				func(req *http.Request) (*http.Response, error) {
+					condition := req.Header.Get(tt.conditional)
+					conditions = append(conditions, condition)
+					if condition == tt.value {
+						resp := httpmock.NewStringResponse(http.StatusNotModified, "")
+						resp.Header.Set("X-RateLimit-Remaining", "4999")
+						return resp, nil
+					}
+					resp, err := httpmock.NewJsonResponse(200, []*github.CommitFile{
+						{Filename: github.String("main.go"), Additions: github.Int(10)},
+					})
+					if err != nil {
+						return nil, err
+					}
+					resp.Header.Set(tt.header, tt.value)
+					resp.Header.Set("X-RateLimit-Remaining", "5000")
+					return resp, nil
+				},
+			)
+
+			dir := filepath.Join(t.TempDir(), "cache")
+			var transport *gh.CacheTransport
+			for i := 0; i < 2; i++ {
+				// A transport is recreated on every request if responses are cached in the directory, so that they're
+				// reused across processes.
+				if transport == nil || tt.persistent {
+					var err error
+					cacheDir := ""
+					if tt.persistent {
+						cacheDir = dir
+					}
+					transport, err = gh.NewCacheTransport(mock, cacheDir)
+					require.NoError(t, err)
+				}
+				client := github.NewClient(&http.Client{Transport: transport})
+				files, resp, err := client.PullRequests.ListFiles(
+					context.Background(),
+					"kkohtaka", "gh-actions-pr-size", 42,
+					nil,
+				)
+				require.NoError(t, err)
+				require.Len(t, files, 1)
+				assert.Equal(t, "main.go", files[0].GetFilename())
+				assert.Equal(t, 5000-i, resp.Rate.Remaining)
+			}
+			assert.Equal(t, []string{"", tt.value}, conditions)
+
+			entries, _ := os.ReadDir(dir)
+			assert.Len(t, entries, tt.wantFileCount)
+		})
+	}
+}
+
+func TestCacheTransportIgnoresUncacheableRequests(t *testing.T) {
+	mock := httpmock.NewMockTransport()
+	var conditions []string
+	mock.RegisterResponder(
+		"POST",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+		func(req *http.Request) (*http.Response, error) {
+			conditions = append(conditions, req.Header.Get("If-None-Match"))
+			resp := httpmock.NewStringResponse(200, `[]`)
+			resp.Header.Set("ETag", `"abc"`)
+			return resp, nil
+		},
+	)
+	mock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+		func(req *http.Request) (*http.Response, error) {
+			conditions = append(conditions, req.Header.Get("If-None-Match"))
+			return httpmock.NewStringResponse(200, `[]`), nil
+		},
+	)
+
+	transport, err := gh.NewCacheTransport(mock, "")
+	require.NoError(t, err)
+	client := github.NewClient(&http.Client{Transport: transport})
+	for i := 0; i < 2; i++ {
+		_, _, err := client.Issues.AddLabelsToIssue(
+			context.Background(),
+			"kkohtaka", "gh-actions-pr-size", 42,
+			[]string{"size/S"},
+		)
+		require.NoError(t, err)
+		_, _, err = client.Issues.ListLabelsByIssue(context.Background(), "kkohtaka", "gh-actions-pr-size", 42, nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"", "", "", ""}, conditions)
+}
+
+func TestCacheTransportPrunesDirectory(t *testing.T) {
+	mock := httpmock.NewMockTransport()
+	mock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+		func(req *http.Request) (*http.Response, error) {
+			resp, err := httpmock.NewJsonResponse(200, []*github.CommitFile{})
+			if err != nil {
+				return nil, err
+			}
+			resp.Header.Set("ETag", `"abc"`)
+			return resp, nil
+		},
+	)
+
+	dir := t.TempDir()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	for _, name := range []string{"old.json", "old.json.123.tmp", "recent.json", "README"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+		if name != "recent.json" {
+			require.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+
+	transport, err := gh.NewCacheTransport(mock, dir)
+	require.NoError(t, err)
+	client := github.NewClient(&http.Client{Transport: transport})
+	_, _, err = client.PullRequests.ListFiles(context.Background(), "kkohtaka", "gh-actions-pr-size", 42, nil)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Len(t, names, 3)
+	assert.Contains(t, names, "recent.json")
+	assert.Contains(t, names, "README")
+	assert.NotContains(t, names, "old.json")
+	assert.NotContains(t, names, "old.json.123.tmp")
+}
@@ -0,0 +1,108 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v29/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// maxAnnotationsPerRequest is the maximum number of annotations which Checks API accepts in a single request.
+	maxAnnotationsPerRequest = 50
+	// maxSummaryLength is the maximum number of characters of a summary which Checks API accepts.
+	maxSummaryLength = 65535
+	// truncatedSuffix is appended to a summary truncated to the limit.
+	truncatedSuffix = "\n\n…(truncated)"
+)
+
+// CheckRun is a completed check run which reports the size of a pull request.
+type CheckRun struct {
+	// Name is a name of the check run.
+	Name string
+	// HeadSHA is a SHA of a commit which the check run is created on.
+	HeadSHA string
+	// Conclusion is one of "success", "neutral" and "failure".
+	Conclusion string
+	// Title is a short description of the result.
+	Title string
+	// Summary is a Markdown document which describes the result.
+	Summary string
+	// Annotations are annotations on files.
+	Annotations []*github.CheckRunAnnotation
+}
+
+// CreateCheckRun creates a completed check run.  Annotations exceeding the limit of a single request are added by
+// updating the check run.
+func CreateCheckRun(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+	run *CheckRun,
+) error {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"sha", run.HeadSHA,
+		"name", run.Name,
+	)
+
+	summary := truncateSummary(run.Summary)
+	annotations := run.Annotations
+	batch := func() []*github.CheckRunAnnotation {
+		n := len(annotations)
+		if n > maxAnnotationsPerRequest {
+			n = maxAnnotationsPerRequest
+		}
+		res := annotations[:n]
+		annotations = annotations[n:]
+		return res
+	}
+
+	now := github.Timestamp{Time: time.Now()}
+	created, _, err := client.Checks.CreateCheckRun(ctx, owner, repo, github.CreateCheckRunOptions{
+		Name:        run.Name,
+		HeadSHA:     run.HeadSHA,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(run.Conclusion),
+		CompletedAt: &now,
+		Output: &github.CheckRunOutput{
+			Title:       github.String(run.Title),
+			Summary:     github.String(summary),
+			Annotations: batch(),
+		},
+	})
+	if err != nil {
+		logger.Error(err, "Failed to create a check run")
+		return fmt.Errorf("create a check run: %w", err)
+	}
+	logger = logger.WithValues("id", created.GetID())
+	logger.Info("A check run was created", "conclusion", run.Conclusion)
+
+	for len(annotations) > 0 {
+		if _, _, err := client.Checks.UpdateCheckRun(ctx, owner, repo, created.GetID(), github.UpdateCheckRunOptions{
+			Name: run.Name,
+			Output: &github.CheckRunOutput{
+				Title:       github.String(run.Title),
+				Summary:     github.String(summary),
+				Annotations: batch(),
+			},
+		}); err != nil {
+			logger.Error(err, "Failed to add annotations to a check run")
+			return fmt.Errorf("update a check run: %w", err)
+		}
+	}
+	return nil
+}
+
+// truncateSummary truncates the summary to the limit of Checks API, so that a long report never fails a check run.
+func truncateSummary(summary string) string {
+	if utf8.RuneCountInString(summary) <= maxSummaryLength {
+		return summary
+	}
+	runes := []rune(summary)
+	return string(runes[:maxSummaryLength-utf8.RuneCountInString(truncatedSuffix)]) + truncatedSuffix
+}
@@ -0,0 +1,178 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCheckRun(t *testing.T) {
+	tcs := []struct {
+		name            string
+		annotations     int
+		wantAnnotations []int
+	}{
+		{
+			name:            "The check run has no annotations.",
+			wantAnnotations: []int{0},
+		},
+		{
+			name:            "Annotations fit in a single request.",
+			annotations:     50,
+			wantAnnotations: []int{50},
+		},
+		{
+			name:            "Annotations are split into multiple requests.",
+			annotations:     120,
+			wantAnnotations: []int{50, 50, 20},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			var (
+				gotCreated      map[string]interface{}
+				gotAnnotations  []int
+				annotationCount = func(output *github.CheckRunOutput) int {
+					if output == nil {
+						return 0
+					}
+					return len(output.Annotations)
+				}
+			)
+			const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/check-runs"
+			httpmock.RegisterResponder(
+				"POST",
+				baseURL,
+				func(req *http.Request) (*http.Response, error) {
+					var opts github.CreateCheckRunOptions
+					if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+						return nil, err
+					}
+					gotCreated = map[string]interface{}{
+						"name":       opts.Name,
+						"head_sha":   opts.HeadSHA,
+						"status":     opts.GetStatus(),
+						"conclusion": opts.GetConclusion(),
+						"title":      opts.Output.GetTitle(),
+						"summary":    opts.Output.GetSummary(),
+					}
+					gotAnnotations = append(gotAnnotations, annotationCount(opts.Output))
+					return httpmock.NewJsonResponse(201, &github.CheckRun{ID: github.Int64(7)})
+				},
+			)
+			httpmock.RegisterResponder(
+				"PATCH",
+				baseURL+"/7",
+				func(req *http.Request) (*http.Response, error) {
+					var opts github.UpdateCheckRunOptions
+					if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+						return nil, err
+					}
+					gotAnnotations = append(gotAnnotations, annotationCount(opts.Output))
+					return httpmock.NewJsonResponse(200, &github.CheckRun{ID: github.Int64(7)})
+				},
+			)
+
+			var annotations []*github.CheckRunAnnotation
+			for i := 0; i < tt.annotations; i++ {
+				annotations = append(annotations, &github.CheckRunAnnotation{
+					Path:            github.String(fmt.Sprintf("file%d.go", i)),
+					StartLine:       github.Int(1),
+					EndLine:         github.Int(1),
+					AnnotationLevel: github.String("notice"),
+					Message:         github.String("message"),
+				})
+			}
+			err := gh.CreateCheckRun(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				&gh.CheckRun{
+					Name:        "PR size",
+					HeadSHA:     "abc",
+					Conclusion:  "success",
+					Title:       "L",
+					Summary:     "summary",
+					Annotations: annotations,
+				},
+			)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{
+				"name":       "PR size",
+				"head_sha":   "abc",
+				"status":     "completed",
+				"conclusion": "success",
+				"title":      "L",
+				"summary":    "summary",
+			}, gotCreated)
+			assert.Equal(t, tt.wantAnnotations, gotAnnotations)
+		})
+	}
+}
+
+func TestCreateCheckRunTruncatesSummary(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	var got string
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/check-runs",
+		func(req *http.Request) (*http.Response, error) {
+			var opts github.CreateCheckRunOptions
+			if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+				return nil, err
+			}
+			got = opts.Output.GetSummary()
+			return httpmock.NewJsonResponse(201, &github.CheckRun{ID: github.Int64(7)})
+		},
+	)
+
+	err := gh.CreateCheckRun(context.Background(), github.NewClient(client), "kkohtaka", "gh-actions-pr-size", &gh.CheckRun{
+		Name:       "PR size",
+		HeadSHA:    "abc",
+		Conclusion: "success",
+		Title:      "title",
+		Summary:    strings.Repeat("行", 70000),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 65535, utf8.RuneCountInString(got))
+	assert.True(t, strings.HasSuffix(got, "…(truncated)"))
+}
+
+func TestCreateCheckRunReturnsError(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/check-runs",
+		httpmock.NewErrorResponder(fmt.Errorf("test for error handling")),
+	)
+
+	err := gh.CreateCheckRun(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		&gh.CheckRun{Name: "PR size", HeadSHA: "abc", Conclusion: "success"},
+	)
+	assert.ErrorContains(t, err, "create a check run: ")
+}
@@ -0,0 +1,144 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v29/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// commandPrefix is a prefix of slash commands written in comments on pull requests.
+const commandPrefix = "/size"
+
+const (
+	// VerbRecompute is a verb of a command which recomputes the size and clears an override.
+	VerbRecompute = "recompute"
+	// VerbOverride is a verb of a command which pins the size to a tier.
+	VerbOverride = "override"
+)
+
+// Command is a slash command written in a comment on a pull request (e.g. "/size override L").
+type Command struct {
+	// Verb is either VerbRecompute or VerbOverride.
+	Verb string
+	// Tier is a name of a tier which the size is pinned to by VerbOverride.
+	Tier string
+}
+
+// ParseCommand returns a command in the first line of the comment body starting with "/size".  If no such line exists,
+// it returns nil.  If the line is malformed, it returns an error.
+func ParseCommand(body string) (*Command, error) {
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != commandPrefix {
+			continue
+		}
+		switch {
+		case len(fields) == 2 && fields[1] == VerbRecompute:
+			return &Command{Verb: VerbRecompute}, nil
+		case len(fields) == 3 && fields[1] == VerbOverride:
+			return &Command{Verb: VerbOverride, Tier: fields[2]}, nil
+		default:
+			return nil, fmt.Errorf(
+				"invalid command %q: %q or %q is required",
+				strings.TrimSpace(line), commandPrefix+" "+VerbRecompute, commandPrefix+" "+VerbOverride+" <tier>",
+			)
+		}
+	}
+	return nil, nil
+}
+
+// CanPush reports whether the user has write permission on the repository.  Users which don't exist (e.g. deleted
+// accounts) have no permission.
+func CanPush(ctx context.Context, client *github.Client, owner, repo, user string) (bool, error) {
+	level, resp, err := client.Repositories.GetPermissionLevel(ctx, owner, repo, user)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("get a permission level of %q: %w", user, err)
+	}
+	switch level.GetPermission() {
+	case "admin", "maintain", "write":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Override is a tier which the size of a pull request is pinned to by a command.
+type Override struct {
+	// Tier is a tier which the size is pinned to.
+	Tier Tier
+	// User is a login of a user who wrote the command.
+	User string
+}
+
+// FindOverride returns an override by the latest command on the pull request, or nil if there's no override or it's
+// cleared by a recompute command.  Commands written by users without write permission, and overrides to tiers which
+// don't belong to the sizer are ignored, so the comments themselves are a tamper-proof record of overrides.
+func FindOverride(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+	number int,
+	sizer *Sizer,
+) (*Override, error) {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"number", number,
+	)
+
+	var comments []*github.IssueComment
+	for offset := 0; ; offset++ {
+		page, resp, err := client.Issues.ListComments(
+			ctx,
+			owner, repo, number,
+			&github.IssueListCommentsOptions{
+				ListOptions: github.ListOptions{Page: offset + 1, PerPage: 100},
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list comments: %w", err)
+		}
+		comments = append(comments, page...)
+		if offset+1 >= resp.LastPage {
+			break
+		}
+	}
+
+	permitted := make(map[string]bool)
+	for i := len(comments) - 1; i >= 0; i-- {
+		comment := comments[i]
+		cmd, err := ParseCommand(comment.GetBody())
+		if err != nil || cmd == nil {
+			continue
+		}
+		user := comment.GetUser().GetLogin()
+		ok, cached := permitted[user]
+		if !cached {
+			if ok, err = CanPush(ctx, client, owner, repo, user); err != nil {
+				return nil, err
+			}
+			permitted[user] = ok
+		}
+		if !ok {
+			logger.Info("Ignored a command by a user without write permission", "user", user, "comment", comment.GetID())
+			continue
+		}
+		if cmd.Verb == VerbRecompute {
+			return nil, nil
+		}
+		tier := sizer.Index(cmd.Tier)
+		if tier < 0 {
+			logger.Info("Ignored an override to an unknown tier", "tier", cmd.Tier, "comment", comment.GetID())
+			continue
+		}
+		return &Override{Tier: sizer.Tiers()[tier], User: user}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,177 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	tcs := []struct {
+		name    string
+		body    string
+		want    *gh.Command
+		wantErr string
+	}{
+		{
+			name: "The comment has no command.",
+			body: "LGTM\n/sizes are fine",
+		},
+		{
+			name: "The comment has a recompute command.",
+			body: "Rebased.\n/size recompute\n",
+			want: &gh.Command{Verb: gh.VerbRecompute},
+		},
+		{
+			name: "The comment has an override command.",
+			body: "  /size   override L\r\nMostly generated code.",
+			want: &gh.Command{Verb: gh.VerbOverride, Tier: "L"},
+		},
+		{
+			name:    "The override command has no tier.",
+			body:    "/size override",
+			wantErr: `invalid command "/size override": "/size recompute" or "/size override <tier>" is required`,
+		},
+		{
+			name:    "The command has an unknown verb.",
+			body:    "/size shrink",
+			wantErr: `invalid command "/size shrink"`,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gh.ParseCommand(tt.body)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindOverride(t *testing.T) {
+	newComment := func(user, body string) *github.IssueComment {
+		return &github.IssueComment{User: &github.User{Login: github.String(user)}, Body: github.String(body)}
+	}
+	tcs := []struct {
+		name     string
+		comments []*github.IssueComment
+		want     *gh.Override
+	}{
+		{
+			name:     "No command is written.",
+			comments: []*github.IssueComment{newComment("maintainer", "LGTM")},
+		},
+		{
+			name: "The latest command is an override.",
+			comments: []*github.IssueComment{
+				newComment("maintainer", "/size override S"),
+				newComment("maintainer", "/size recompute"),
+				newComment("maintainer", "/size override L"),
+				newComment("maintainer", "Thanks!"),
+			},
+			want: &gh.Override{Tier: tier("L"), User: "maintainer"},
+		},
+		{
+			name: "The latest command is a recompute.",
+			comments: []*github.IssueComment{
+				newComment("maintainer", "/size override L"),
+				newComment("maintainer", "/size recompute"),
+			},
+		},
+		{
+			name: "Commands by users without write permission are ignored.",
+			comments: []*github.IssueComment{
+				newComment("maintainer", "/size override M"),
+				newComment("contributor", "/size override XS"),
+				newComment("ghost", "/size recompute"),
+			},
+			want: &gh.Override{Tier: tier("M"), User: "maintainer"},
+		},
+		{
+			name: "Overrides to unknown tiers and malformed commands are ignored.",
+			comments: []*github.IssueComment{
+				newComment("maintainer", "/size override XL"),
+				newComment("maintainer", "/size override XXXL"),
+				newComment("maintainer", "/size"),
+			},
+			want: &gh.Override{Tier: tier("XL"), User: "maintainer"},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			httpmock.RegisterResponder(
+				"GET",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+				httpmock.NewJsonResponderOrPanic(200, tt.comments),
+			)
+			httpmock.RegisterResponder(
+				"GET",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/maintainer/permission",
+				httpmock.NewJsonResponderOrPanic(200, &github.RepositoryPermissionLevel{Permission: github.String("write")}),
+			)
+			httpmock.RegisterResponder(
+				"GET",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/contributor/permission",
+				httpmock.NewJsonResponderOrPanic(200, &github.RepositoryPermissionLevel{Permission: github.String("read")}),
+			)
+			httpmock.RegisterResponder(
+				"GET",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/ghost/permission",
+				httpmock.NewStringResponder(404, `{"message": "Not Found"}`),
+			)
+
+			got, err := gh.FindOverride(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				42,
+				gh.DefaultSizer(),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindOverrideReturnsError(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+		httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{
+			{User: &github.User{Login: github.String("maintainer")}, Body: github.String("/size recompute")},
+		}),
+	)
+	httpmock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/collaborators/maintainer/permission",
+		httpmock.NewStringResponder(500, `{"message": "Server Error"}`),
+	)
+
+	_, err := gh.FindOverride(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		42,
+		gh.DefaultSizer(),
+	)
+	assert.ErrorContains(t, err, `get a permission level of "maintainer": `)
+}
@@ -0,0 +1,162 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v29/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// CommentMarker is a hidden HTML comment which identifies a comment posted by this tool.
+	CommentMarker = "<!-- gh-actions-pr-size -->"
+
+	// GitHubActionsBot is a login of the bot which GITHUB_TOKEN of GitHub Actions acts as.
+	GitHubActionsBot = "github-actions[bot]"
+)
+
+// AuthenticatedLogin returns a login of the user which the client is authenticated as.  Installation access tokens
+// can't get the authenticated user, so GitHubActionsBot is returned for them, assuming that it's GITHUB_TOKEN.
+// Use AppBotLogin for installation access tokens of other GitHub Apps.
+func AuthenticatedLogin(ctx context.Context, client *github.Client) (string, error) {
+	user, _, err := client.Users.Get(ctx, "")
+	var errResp *github.ErrorResponse
+	switch {
+	case errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusForbidden:
+		return GitHubActionsBot, nil
+	case err != nil:
+		return "", fmt.Errorf("get the authenticated user: %w", err)
+	}
+	return user.GetLogin(), nil
+}
+
+// AppBotLogin returns a login of the bot of the GitHub App, which comments with installation access tokens of the app.
+// The client must be authenticated as the app, e.g. by AppJWTSource.
+func AppBotLogin(ctx context.Context, appClient *github.Client) (string, error) {
+	app, _, err := appClient.Apps.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("get the authenticated app: %w", err)
+	}
+	return app.GetSlug() + "[bot]", nil
+}
+
+// findStickyComment returns a comment starting with CommentMarker and written by the author on the pull request, or
+// nil if it doesn't exist.  Comments by others are ignored even if they start with the marker, so that nobody can make
+// this tool edit or delete their comments.
+func findStickyComment(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+	number int,
+	author string,
+) (*github.IssueComment, error) {
+	for offset := 0; ; offset++ {
+		comments, resp, err := client.Issues.ListComments(
+			ctx,
+			owner, repo, number,
+			&github.IssueListCommentsOptions{
+				ListOptions: github.ListOptions{Page: offset + 1, PerPage: 100},
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list comments: %w", err)
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.GetBody(), CommentMarker) && strings.EqualFold(comment.GetUser().GetLogin(), author) {
+				return comment, nil
+			}
+		}
+		if offset+1 >= resp.LastPage {
+			break
+		}
+	}
+	return nil, nil
+}
+
+// SetStickyComment creates a comment with the body on the pull request, or updates the comment if it was already
+// created by a previous run.  The author is a login which the client comments as.
+func SetStickyComment(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+	number int,
+	author, body string,
+) error {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"number", number,
+	)
+
+	body = CommentMarker + "\n" + body
+	comment, err := findStickyComment(ctx, client, owner, repo, number, author)
+	if err != nil {
+		logger.Error(err, "Failed to find a comment on a pull request")
+		return err
+	}
+
+	if comment == nil {
+		if _, _, err := client.Issues.CreateComment(
+			ctx,
+			owner, repo, number,
+			&github.IssueComment{Body: github.String(body)},
+		); err != nil {
+			logger.Error(err, "Failed to create a comment on a pull request")
+			return fmt.Errorf("create a comment: %w", err)
+		}
+		logger.Info("A comment was created on the pull request")
+		return nil
+	}
+
+	logger = logger.WithValues("comment", comment.GetID())
+	if comment.GetBody() == body {
+		logger.Info("The comment on the pull request is up to date")
+		return nil
+	}
+	if _, _, err := client.Issues.EditComment(
+		ctx,
+		owner, repo, comment.GetID(),
+		&github.IssueComment{Body: github.String(body)},
+	); err != nil {
+		logger.Error(err, "Failed to edit a comment on a pull request")
+		return fmt.Errorf("edit a comment: %w", err)
+	}
+	logger.Info("The comment on the pull request was updated")
+	return nil
+}
+
+// DeleteStickyComment deletes a comment created by SetStickyComment with the author if it exists.
+func DeleteStickyComment(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+	number int,
+	author string,
+) error {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"number", number,
+	)
+
+	comment, err := findStickyComment(ctx, client, owner, repo, number, author)
+	if err != nil {
+		logger.Error(err, "Failed to find a comment on a pull request")
+		return err
+	}
+	if comment == nil {
+		return nil
+	}
+
+	logger = logger.WithValues("comment", comment.GetID())
+	if _, err := client.Issues.DeleteComment(ctx, owner, repo, comment.GetID()); err != nil {
+		logger.Error(err, "Failed to delete a comment on a pull request")
+		return fmt.Errorf("delete a comment: %w", err)
+	}
+	logger.Info("The comment on the pull request was deleted")
+	return nil
+}
@@ -0,0 +1,275 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bot is the author of comments posted by this tool in tests.
+var bot = &github.User{Login: github.String(gh.GitHubActionsBot)}
+
+func TestSetStickyComment(t *testing.T) {
+	tcs := []struct {
+		name     string
+		comments [][]*github.IssueComment
+
+		wantCreated []string
+		wantEdited  map[int64]string
+	}{
+		{
+			name:        "The pull request has no comments.",
+			comments:    [][]*github.IssueComment{{}},
+			wantCreated: []string{gh.CommentMarker + "\nnew body"},
+		},
+		{
+			name: "The pull request has a comment created by a previous run.",
+			comments: [][]*github.IssueComment{
+				{
+					{ID: github.Int64(1), Body: github.String("Looks good to me")},
+				},
+				{
+					{ID: github.Int64(2), Body: github.String(gh.CommentMarker + "\nold body"), User: bot},
+				},
+			},
+			wantEdited: map[int64]string{2: gh.CommentMarker + "\nnew body"},
+		},
+		{
+			name: "Another user wrote a comment with the marker.",
+			comments: [][]*github.IssueComment{
+				{
+					{
+						ID:   github.Int64(1),
+						Body: github.String(gh.CommentMarker + "\nold body"),
+						User: &github.User{Login: github.String("someone")},
+					},
+				},
+			},
+			wantCreated: []string{gh.CommentMarker + "\nnew body"},
+		},
+		{
+			name: "The comment is up to date.",
+			comments: [][]*github.IssueComment{
+				{
+					{ID: github.Int64(2), Body: github.String(gh.CommentMarker + "\nnew body"), User: bot},
+				},
+			},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			var (
+				gotCreated []string
+				gotEdited  map[int64]string
+			)
+			const repoURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size"
+			const baseURL = repoURL + "/issues/42/comments"
+			httpmock.RegisterResponder(
+				"GET",
+				baseURL,
+				func(req *http.Request) (*http.Response, error) {
+					page := getPage(req)
+					resp, err := httpmock.NewJsonResponse(200, tt.comments[page-1])
+					if err != nil {
+						return nil, err
+					}
+					resp.Header.Set("Link", generateLinkHeaderValue(baseURL, page, len(tt.comments)))
+					return resp, nil
+				},
+			)
+			httpmock.RegisterResponder(
+				"POST",
+				baseURL,
+				func(req *http.Request) (*http.Response, error) {
+					var c github.IssueComment
+					if err := json.NewDecoder(req.Body).Decode(&c); err != nil {
+						return nil, err
+					}
+					gotCreated = append(gotCreated, c.GetBody())
+					return httpmock.NewJsonResponse(201, c)
+				},
+			)
+			httpmock.RegisterResponder(
+				"PATCH",
+				fmt.Sprintf(`=~^%s/issues/comments/(\d+)$`, repoURL),
+				func(req *http.Request) (*http.Response, error) {
+					id, err := httpmock.GetSubmatchAsInt(req, 1)
+					if err != nil {
+						return nil, err
+					}
+					var c github.IssueComment
+					if err := json.NewDecoder(req.Body).Decode(&c); err != nil {
+						return nil, err
+					}
+					if gotEdited == nil {
+						gotEdited = make(map[int64]string)
+					}
+					gotEdited[id] = c.GetBody()
+					return httpmock.NewJsonResponse(200, c)
+				},
+			)
+
+			err := gh.SetStickyComment(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				42,
+				gh.GitHubActionsBot,
+				"new body",
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCreated, gotCreated)
+			assert.Equal(t, tt.wantEdited, gotEdited)
+		})
+	}
+}
+
+func TestDeleteStickyComment(t *testing.T) {
+	tcs := []struct {
+		name        string
+		comments    []*github.IssueComment
+		wantDeleted []string
+	}{
+		{
+			name: "The pull request has a comment created by a previous run.",
+			comments: []*github.IssueComment{
+				{ID: github.Int64(1), Body: github.String("Looks good to me")},
+				{ID: github.Int64(2), Body: github.String(gh.CommentMarker + "\nbody"), User: bot},
+			},
+			wantDeleted: []string{"2"},
+		},
+		{
+			name: "Another user wrote a comment with the marker.",
+			comments: []*github.IssueComment{
+				{
+					ID:   github.Int64(1),
+					Body: github.String(gh.CommentMarker + "\nbody"),
+					User: &github.User{Login: github.String("someone")},
+				},
+			},
+		},
+		{
+			name: "The pull request has no comments created by this tool.",
+			comments: []*github.IssueComment{
+				{ID: github.Int64(1), Body: github.String("Looks good to me")},
+			},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			const repoURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size"
+			httpmock.RegisterResponder(
+				"GET",
+				repoURL+"/issues/42/comments",
+				httpmock.NewJsonResponderOrPanic(200, tt.comments),
+			)
+			var gotDeleted []string
+			httpmock.RegisterResponder(
+				"DELETE",
+				fmt.Sprintf(`=~^%s/issues/comments/(\d+)$`, repoURL),
+				func(req *http.Request) (*http.Response, error) {
+					gotDeleted = append(gotDeleted, httpmock.MustGetSubmatch(req, 1))
+					return httpmock.NewBytesResponse(204, nil), nil
+				},
+			)
+
+			err := gh.DeleteStickyComment(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				42,
+				gh.GitHubActionsBot,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDeleted, gotDeleted)
+		})
+	}
+}
+
+func TestSetStickyCommentReturnsError(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments"
+	httpmock.RegisterResponder("GET", baseURL, httpmock.NewJsonResponderOrPanic(200, []*github.IssueComment{}))
+	httpmock.RegisterResponder("POST", baseURL, httpmock.NewErrorResponder(fmt.Errorf("test for error handling")))
+
+	err := gh.SetStickyComment(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		42,
+		gh.GitHubActionsBot,
+		"body",
+	)
+	assert.ErrorContains(t, err, "create a comment: ")
+}
+
+func TestAuthenticatedLogin(t *testing.T) {
+	tcs := []struct {
+		name      string
+		responder httpmock.Responder
+		want      string
+	}{
+		{
+			name:      "The client is authenticated as a user.",
+			responder: httpmock.NewJsonResponderOrPanic(200, &github.User{Login: github.String("kkohtaka")}),
+			want:      "kkohtaka",
+		},
+		{
+			name: "The client is authenticated by an installation access token.",
+			responder: httpmock.NewStringResponder(
+				403,
+				`{"message": "Resource not accessible by integration"}`,
+			),
+			want: gh.GitHubActionsBot,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+			httpmock.RegisterResponder("GET", "https://api.github.com/user", tt.responder)
+
+			got, err := gh.AuthenticatedLogin(context.Background(), github.NewClient(client))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAppBotLogin(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"GET",
+		"https://api.github.com/app",
+		httpmock.NewJsonResponderOrPanic(200, &github.App{Slug: github.String("pr-size")}),
+	)
+
+	got, err := gh.AppBotLogin(context.Background(), github.NewClient(client))
+	require.NoError(t, err)
+	assert.Equal(t, "pr-size[bot]", got)
+}
@@ -0,0 +1,270 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gitattributes"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/glob"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FileFilter decides whether a changed file is counted in the size of a pull request.
+type FileFilter interface {
+	// Exclude returns a non-empty reason if the file should not be counted.
+	Exclude(ctx context.Context, filename string) (string, error)
+}
+
+// excludeReason returns a reason given by the first filter which excludes the file.
+func excludeReason(ctx context.Context, filters []FileFilter, filename string) (string, error) {
+	for _, f := range filters {
+		reason, err := f.Exclude(ctx, filename)
+		if err != nil {
+			return "", err
+		}
+		if reason != "" {
+			return reason, nil
+		}
+	}
+	return "", nil
+}
+
+// linguistAttributes are attributes which mark files to be excluded from the size of a pull request.
+var linguistAttributes = []string{
+	"linguist-generated",
+	"linguist-vendored",
+}
+
+// Tree reads files in a tree of a repository at a ref.
+type Tree interface {
+	// Find returns paths of all files with the name in the tree.  If the tree is too large to be listed at once, it
+	// returns false, and the files are looked up one by one by ReadFile instead.
+	Find(ctx context.Context, name string) ([]string, bool, error)
+	// ReadFile returns the content of the file at the path, or ErrFileNotFound if it doesn't exist.
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+}
+
+// RepositoryTree is a tree of a repository on GitHub at a ref.
+type RepositoryTree struct {
+	client           *github.Client
+	owner, repo, ref string
+}
+
+var _ Tree = &RepositoryTree{}
+
+// NewRepositoryTree returns a tree of the repository at the ref.  If the ref is empty, the default branch is used.
+func NewRepositoryTree(client *github.Client, owner, repo, ref string) *RepositoryTree {
+	return &RepositoryTree{
+		client: client,
+		owner:  owner,
+		repo:   repo,
+		ref:    ref,
+	}
+}
+
+// Find implements Tree.
+func (t *RepositoryTree) Find(ctx context.Context, name string) ([]string, bool, error) {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", t.owner,
+		"repo", t.repo,
+		"ref", t.ref,
+	)
+
+	tree, _, err := t.client.Git.GetTree(ctx, t.owner, t.repo, t.ref, true)
+	if err != nil {
+		logger.Error(err, "Failed to get a tree of a repository")
+		return nil, false, fmt.Errorf("get a tree: %w", err)
+	}
+	if tree.GetTruncated() {
+		logger.Info("A tree of the repository is truncated, so files are looked up one by one", "name", name)
+		return nil, false, nil
+	}
+	var res []string
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" && path.Base(entry.GetPath()) == name {
+			res = append(res, entry.GetPath())
+		}
+	}
+	return res, true, nil
+}
+
+// ReadFile implements Tree.
+func (t *RepositoryTree) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	return GetRepositoryFile(ctx, t.client, t.owner, t.repo, t.ref, path)
+}
+
+// LinguistFilter excludes files marked as linguist-generated or linguist-vendored by .gitattributes files in a tree of
+// a repository.
+type LinguistFilter struct {
+	tree Tree
+
+	// existing is a set of paths of .gitattributes files in the tree.  It's nil until the tree is listed or when the
+	// tree is too large to be listed at once.
+	existing map[string]struct{}
+	listed   bool
+	// files caches .gitattributes files by their directories.  A nil value means the directory has no file.
+	files map[string]*gitattributes.File
+}
+
+var _ FileFilter = &LinguistFilter{}
+
+// NewLinguistFilter returns a filter which reads .gitattributes files at the ref of the repository.  The files are
+// fetched lazily and only in the directories containing changed files.
+func NewLinguistFilter(client *github.Client, owner, repo, ref string) *LinguistFilter {
+	return NewTreeLinguistFilter(NewRepositoryTree(client, owner, repo, ref))
+}
+
+// NewTreeLinguistFilter returns a filter which reads .gitattributes files in the tree.
+func NewTreeLinguistFilter(tree Tree) *LinguistFilter {
+	return &LinguistFilter{
+		tree:  tree,
+		files: make(map[string]*gitattributes.File),
+	}
+}
+
+// Exclude implements FileFilter.
+func (f *LinguistFilter) Exclude(ctx context.Context, filename string) (string, error) {
+	attrs, err := f.attributes(ctx, path.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+	for _, name := range linguistAttributes {
+		if attrs.IsTrue(filename, name) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// Empty reports whether the tree has no .gitattributes files, in which case the filter excludes no files.  It returns
+// false if the tree is too large to be listed at once.
+func (f *LinguistFilter) Empty(ctx context.Context) (bool, error) {
+	if err := f.listTree(ctx); err != nil {
+		return false, err
+	}
+	return f.existing != nil && len(f.existing) == 0, nil
+}
+
+// attributes returns attributes assigned by .gitattributes files in the directory and its ancestors.
+func (f *LinguistFilter) attributes(ctx context.Context, dir string) (*gitattributes.Attributes, error) {
+	if err := f.listTree(ctx); err != nil {
+		return nil, err
+	}
+
+	var files []*gitattributes.File
+	for _, d := range ancestors(dir) {
+		file, err := f.file(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	return gitattributes.New(files...), nil
+}
+
+// listTree lists paths of .gitattributes files in the tree so that directories without them aren't queried.
+func (f *LinguistFilter) listTree(ctx context.Context) error {
+	if f.listed {
+		return nil
+	}
+	paths, complete, err := f.tree.Find(ctx, gitattributes.FileName)
+	if err != nil {
+		return err
+	}
+	f.listed = true
+	if !complete {
+		return nil
+	}
+	f.existing = make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		f.existing[p] = struct{}{}
+	}
+	return nil
+}
+
+// file returns a .gitattributes file in the directory, or nil if it doesn't exist.
+func (f *LinguistFilter) file(ctx context.Context, dir string) (*gitattributes.File, error) {
+	if file, ok := f.files[dir]; ok {
+		return file, nil
+	}
+
+	p := path.Join(dir, gitattributes.FileName)
+	if f.existing != nil {
+		if _, ok := f.existing[p]; !ok {
+			f.files[dir] = nil
+			return nil, nil
+		}
+	}
+	data, err := f.tree.ReadFile(ctx, p)
+	if errors.Is(err, ErrFileNotFound) {
+		f.files[dir] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get %q: %w", p, err)
+	}
+	file := gitattributes.Parse(dir, data)
+	f.files[dir] = file
+	return file, nil
+}
+
+// ancestors returns the directory and its ancestors from the root ("") to the directory itself.
+func ancestors(dir string) []string {
+	res := []string{""}
+	if dir == "." || dir == "" {
+		return res
+	}
+	segments := strings.Split(dir, "/")
+	for i := range segments {
+		res = append(res, strings.Join(segments[:i+1], "/"))
+	}
+	return res
+}
+
+// GlobFilter excludes files by glob patterns, in which "**" matches zero or more directories.
+type GlobFilter struct {
+	include []string
+	exclude []string
+}
+
+var _ FileFilter = &GlobFilter{}
+
+// NewGlobFilter returns a filter which counts only files matching any of the include patterns, or all files if no
+// include patterns are specified, and excludes files matching any of the exclude patterns.
+func NewGlobFilter(include, exclude []string) (*GlobFilter, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if err := glob.Validate(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return &GlobFilter{include: include, exclude: exclude}, nil
+}
+
+// Exclude implements FileFilter.
+func (f *GlobFilter) Exclude(_ context.Context, filename string) (string, error) {
+	for _, pattern := range f.exclude {
+		if glob.Match(pattern, filename) {
+			return fmt.Sprintf("excluded by %q", pattern), nil
+		}
+	}
+	if len(f.include) == 0 {
+		return "", nil
+	}
+	for _, pattern := range f.include {
+		if glob.Match(pattern, filename) {
+			return "", nil
+		}
+	}
+	return "not included", nil
+}
+
+// Empty reports whether the filter has no patterns, in which case it excludes no files.
+func (f *GlobFilter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
@@ -0,0 +1,78 @@
+package gh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobFilter(t *testing.T) {
+	tcs := []struct {
+		name       string
+		include    []string
+		exclude    []string
+		filename   string
+		wantReason string
+	}{
+		{
+			name:     "No patterns are specified.",
+			filename: "go.sum",
+		},
+		{
+			name:       "The file matches an exclude pattern.",
+			exclude:    []string{"**/go.sum", "**/package-lock.json"},
+			filename:   "web/package-lock.json",
+			wantReason: `excluded by "**/package-lock.json"`,
+		},
+		{
+			name:     "The file doesn't match exclude patterns.",
+			exclude:  []string{"**/go.sum"},
+			filename: "main.go",
+		},
+		{
+			name:     "The file matches an include pattern.",
+			include:  []string{"pkg/**"},
+			filename: "pkg/gh/gh.go",
+		},
+		{
+			name:       "The file doesn't match include patterns.",
+			include:    []string{"pkg/**"},
+			filename:   "README.md",
+			wantReason: "not included",
+		},
+		{
+			name:       "Exclude patterns take precedence over include patterns.",
+			include:    []string{"pkg/**"},
+			exclude:    []string{"**/__snapshots__/**"},
+			filename:   "pkg/__snapshots__/a.snap",
+			wantReason: `excluded by "**/__snapshots__/**"`,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := gh.NewGlobFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			got, err := f.Exclude(context.Background(), tt.filename)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReason, got)
+		})
+	}
+}
+
+func TestNewGlobFilterReturnsError(t *testing.T) {
+	_, err := gh.NewGlobFilter(nil, []string{"[a"})
+	assert.ErrorContains(t, err, "malformed pattern")
+}
+
+func TestGlobFilterEmpty(t *testing.T) {
+	f, err := gh.NewGlobFilter(nil, nil)
+	require.NoError(t, err)
+	assert.True(t, f.Empty())
+
+	f, err = gh.NewGlobFilter(nil, []string{"**/go.sum"})
+	require.NoError(t, err)
+	assert.False(t, f.Empty())
+}
@@ -2,13 +2,57 @@ package gh
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"net/http"
+	"net/url"
 
 	"github.com/google/go-github/v29/github"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// MaxListedFiles is the maximum number of files which GitHub API lists in a pull request.
+const MaxListedFiles = 3000
+
+// ErrFileNotFound is returned when the requested file doesn't exist in a repository.
+var ErrFileNotFound = errors.New("file not found")
+
+// GetRepositoryFile returns the content of a file at the specified ref of a repository.  If the file doesn't exist,
+// the function returns ErrFileNotFound.
+func GetRepositoryFile(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, ref, path string,
+) ([]byte, error) {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"ref", ref,
+		"path", path,
+	)
+
+	file, _, resp, err := client.Repositories.GetContents(
+		ctx,
+		owner, repo, path,
+		&github.RepositoryContentGetOptions{Ref: ref},
+	)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		logger.Error(err, "Failed to get a file in a repository")
+		return nil, fmt.Errorf("get contents: %w", err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%q is not a file", path)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("decode contents: %w", err)
+	}
+	return []byte(content), nil
+}
+
 // getAllPullRequestFiles returns all commit files in a pull request.
 func getAllPullRequestFiles(
 	ctx context.Context,
@@ -42,35 +86,205 @@ func getAllPullRequestFiles(
 	return res, nil
 }
 
-// GetPullRequestSize returns the total number of changed lines of the specified pull request.
+// File is a changed file and its number of changed lines, which is either listed by GitHub API or computed by a local
+// diff.
+type File struct {
+	// Filename is a path of the file at the head.
+	Filename string
+	// Status is a status of the file (e.g. "added", "removed", "modified" or "renamed").
+	Status string
+	// Additions is the number of added lines in the file.
+	Additions int
+	// Deletions is the number of deleted lines in the file.
+	Deletions int
+}
+
+// NewFile returns a file from a commit file listed by GitHub API.
+func NewFile(f *github.CommitFile) *File {
+	return &File{
+		Filename:  f.GetFilename(),
+		Status:    f.GetStatus(),
+		Additions: f.GetAdditions(),
+		Deletions: f.GetDeletions(),
+	}
+}
+
+// Changes returns the number of changed lines in the file.
+func (f *File) Changes() int {
+	return f.Additions + f.Deletions
+}
+
+// ChangedLines is a breakdown of changed lines in a pull request.
+type ChangedLines struct {
+	// Additions is the number of added lines in the counted files.
+	Additions int
+	// Deletions is the number of deleted lines in the counted files.
+	Deletions int
+	// Files is a list of files counted in the size of the pull request.
+	Files []*File
+	// Excluded is a list of files excluded from the size of the pull request.
+	Excluded []ExcludedFile
+	// Unlisted is the number of files which weren't listed.  Their changed lines are included in Additions and
+	// Deletions without being filtered.
+	Unlisted int
+	// Truncated is true if GitHub API didn't list all files, in which case the breakdown by files is incomplete.
+	Truncated bool
+}
+
+// ExcludedFile is a file excluded from the size of a pull request.
+type ExcludedFile struct {
+	File *File
+	// Reason describes why the file was excluded.
+	Reason string
+}
+
+// Total returns the total number of changed lines in the counted files.
+func (c *ChangedLines) Total() int {
+	return c.Additions + c.Deletions
+}
+
+// FileCount returns the number of files counted in the size, including the unlisted ones.
+func (c *ChangedLines) FileCount() int {
+	return len(c.Files) + c.Unlisted
+}
+
+// ExcludedLines returns the total number of changed lines in the excluded files.
+func (c *ChangedLines) ExcludedLines() int {
+	n := 0
+	for _, e := range c.Excluded {
+		n += e.File.Changes()
+	}
+	return n
+}
+
+// GetPullRequestChangedLines returns the number of changed lines of the specified pull request.  Files excluded by
+// any of the filters are not counted.
+//
+// GitHub API lists at most MaxListedFiles files in a pull request.  If the pull request changes more files, the
+// changed lines of the unlisted files are computed from the totals of the pull request and counted without being
+// filtered.  The pull request is fetched if it doesn't have the totals (e.g. it's listed by GitHub API).  The compare
+// API isn't used for the unlisted files since it lists even fewer files.
 func GetPullRequestChangedLines(
 	ctx context.Context,
 	client *github.Client,
 	owner, repo string,
-	number int,
-) (int, error) {
-	files, err := getAllPullRequestFiles(ctx, client, owner, repo, number)
+	pr *github.PullRequest,
+	filters ...FileFilter,
+) (*ChangedLines, error) {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"number", pr.GetNumber(),
+	)
+	ctx = log.IntoContext(ctx, logger)
+
+	commitFiles, err := getAllPullRequestFiles(ctx, client, owner, repo, pr.GetNumber())
+	if err != nil {
+		return nil, fmt.Errorf("get all commit files: %w", err)
+	}
+	files := make([]*File, 0, len(commitFiles))
+	for _, f := range commitFiles {
+		files = append(files, NewFile(f))
+	}
+	res, err := CountChangedLines(ctx, files, filters...)
 	if err != nil {
-		return 0, fmt.Errorf("get all commit files: %w", err)
+		return nil, err
+	}
+	if len(files) < MaxListedFiles {
+		return res, nil
 	}
 
-	// TODO(kkohtaka): Filter out linguist-generated files
+	if pr.ChangedFiles == nil || pr.Additions == nil || pr.Deletions == nil {
+		pr, _, err = client.PullRequests.Get(ctx, owner, repo, pr.GetNumber())
+		if err != nil {
+			logger.Error(err, "Failed to get a pull request")
+			return nil, fmt.Errorf("get a pull request: %w", err)
+		}
+	}
+	if pr.GetChangedFiles() <= len(files) {
+		return res, nil
+	}
+	var additions, deletions int
+	for _, f := range files {
+		additions += f.Additions
+		deletions += f.Deletions
+	}
+	res.Unlisted = pr.GetChangedFiles() - len(files)
+	res.Truncated = true
+	res.Additions += nonNegative(pr.GetAdditions() - additions)
+	res.Deletions += nonNegative(pr.GetDeletions() - deletions)
+	logger.Info("GitHub API didn't list all files of the pull request, so unlisted files are counted without filters",
+		"listed", len(files),
+		"changedFiles", pr.GetChangedFiles(),
+	)
+	return res, nil
+}
+
+// PullRequestTotals returns the number of changed lines of the pull request from its totals without listing files, in
+// which all files are unlisted.  It returns false if the pull request doesn't have the totals (e.g. it's listed by
+// GitHub API).
+func PullRequestTotals(pr *github.PullRequest) (*ChangedLines, bool) {
+	if pr.ChangedFiles == nil || pr.Additions == nil || pr.Deletions == nil {
+		return nil, false
+	}
+	return &ChangedLines{
+		Additions: pr.GetAdditions(),
+		Deletions: pr.GetDeletions(),
+		Unlisted:  pr.GetChangedFiles(),
+	}, true
+}
 
-	change := 0
+// nonNegative returns n if it's positive, or 0 otherwise.
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// CountChangedLines returns the number of changed lines in the files.  Files excluded by any of the filters are not
+// counted.
+func CountChangedLines(ctx context.Context, files []*File, filters ...FileFilter) (*ChangedLines, error) {
+	logger := log.FromContext(ctx)
+
+	res := &ChangedLines{}
 	for _, file := range files {
-		change += *file.Additions + *file.Deletions
+		reason, err := excludeReason(ctx, filters, file.Filename)
+		if err != nil {
+			return nil, fmt.Errorf("filter commit files: %w", err)
+		}
+		if reason != "" {
+			logger.Info("A file was excluded from the size of the pull request",
+				"file", file.Filename,
+				"reason", reason,
+				"lines", file.Changes(),
+			)
+			res.Excluded = append(res.Excluded, ExcludedFile{File: file, Reason: reason})
+			continue
+		}
+		res.Files = append(res.Files, file)
+		res.Additions += file.Additions
+		res.Deletions += file.Deletions
 	}
-	return change, nil
+	return res, nil
 }
 
-// SetLabelOnPullRequest checks the current labels on the pull request.  If there exists a label for pull request size,
-// the function replaces it with the proper label.  Otherwise, the function just attach the proper label.
+// SetLabelOnPullRequest makes the pull request have the label of the size and no other labels of the tiers of the
+// sizer.  The function adds the label first and then removes stale labels of the tiers one by one, so that labels which
+// don't belong to the tiers are never touched, even if someone changes them at the same time.  If the pull request
+// already has the proper set of labels, nothing is updated.
+//
+// If another run labels the pull request with another size concurrently, each run may remove the label of the other
+// as a stale one.  The labels are read again after stale ones are removed, and the label is added back if it's gone, so
+// that the pull request never ends up without a label of the tiers.  Labels of both sizes may remain in that case until
+// the pull request is labeled again.
 func SetLabelOnPullRequest(
 	ctx context.Context,
 	client *github.Client,
 	owner, repo string,
 	number int,
-	size Size,
+	sizer *Sizer,
+	size Tier,
 ) error {
 	logger := log.FromContext(ctx).WithValues(
 		"owner", owner,
@@ -79,6 +293,68 @@ func SetLabelOnPullRequest(
 		"label", size.GetLabel(),
 	)
 
+	current, err := listIssueLabels(ctx, client, owner, repo, number)
+	if err != nil {
+		logger.Error(err, "Failed to list labels on a pull request")
+		return err
+	}
+
+	stale, has := staleLabels(current, sizer.Labels(), size.GetLabel())
+	if has && len(stale) == 0 {
+		logger.Info("The pull request already has the label")
+		return nil
+	}
+	if !has {
+		labels, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repo, number, []string{size.GetLabel()})
+		if err != nil {
+			logger.Error(err, "Failed to add a label to a pull request")
+			return fmt.Errorf("add a label to a pull request: %w", err)
+		}
+		logger.Info("A label was added to the pull request")
+
+		current = current[:0]
+		for _, label := range labels {
+			current = append(current, label.GetName())
+		}
+		stale, _ = staleLabels(current, sizer.Labels(), size.GetLabel())
+		if len(stale) == 0 {
+			return nil
+		}
+	}
+
+	for _, label := range stale {
+		// Labels may contain "/" (e.g. "size/L"), which go-github doesn't escape in the path.
+		resp, err := client.Issues.RemoveLabelForIssue(ctx, owner, repo, number, url.PathEscape(label))
+		switch {
+		case resp != nil && resp.StatusCode == http.StatusNotFound:
+			logger.Info("A stale label was already removed from the pull request", "remove", label)
+		case err != nil:
+			logger.Error(err, "Failed to remove a label from a pull request", "remove", label)
+			return fmt.Errorf("remove a label from a pull request: %w", err)
+		default:
+			logger.Info("A stale label was removed from the pull request", "remove", label)
+		}
+	}
+
+	current, err = listIssueLabels(ctx, client, owner, repo, number)
+	if err != nil {
+		logger.Error(err, "Failed to list labels on a pull request")
+		return err
+	}
+	if _, has := staleLabels(current, sizer.Labels(), size.GetLabel()); has {
+		return nil
+	}
+	if _, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repo, number, []string{size.GetLabel()}); err != nil {
+		logger.Error(err, "Failed to add a label to a pull request")
+		return fmt.Errorf("add a label to a pull request: %w", err)
+	}
+	logger.Info("A label removed by another run was added back to the pull request")
+	return nil
+}
+
+// listIssueLabels returns names of all labels on the issue or the pull request.
+func listIssueLabels(ctx context.Context, client *github.Client, owner, repo string, number int) ([]string, error) {
+	var res []string
 	for offset := 0; ; offset++ {
 		labels, resp, err := client.Issues.ListLabelsByIssue(
 			ctx,
@@ -86,32 +362,34 @@ func SetLabelOnPullRequest(
 			&github.ListOptions{Page: offset + 1, PerPage: 100},
 		)
 		if err != nil {
-			logger.Error(err, "Failed to list labels on a pull request")
-			return fmt.Errorf("list labels by issue: %w", err)
+			return nil, fmt.Errorf("list labels by issue: %w", err)
 		}
 		for _, label := range labels {
-			newLogger := logger.WithValues("remove", label.GetName())
-			if strings.HasPrefix(label.GetName(), labelPrefix) {
-				if label.GetName() == size.GetLabel() {
-					newLogger.Info("The pull request already has the label")
-					return nil
-				}
-				// Remove the current label for pull request size
-				if _, err := client.Issues.RemoveLabelForIssue(ctx, owner, repo, number, label.GetName()); err != nil {
-					newLogger.Error(err, "Failed to remove a label from a pull request")
-					return fmt.Errorf("remove a label from a pull request: %w", err)
-				}
-				newLogger.Info("A label was removed from the pull request")
-			}
+			res = append(res, label.GetName())
 		}
 		if offset+1 >= resp.LastPage {
 			break
 		}
 	}
+	return res, nil
+}
 
-	if _, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repo, number, []string{size.GetLabel()}); err != nil {
-		logger.Error(err, "Failed to add a label to a pull request")
-		return fmt.Errorf("add a label to a pull request: %w", err)
+// staleLabels returns the labels of tiers other than the target label in the current labels, which should be removed.
+// It also reports whether the current labels contain the target label.
+func staleLabels(current, tiers []string, target string) (stale []string, has bool) {
+	known := make(map[string]struct{}, len(tiers))
+	for _, label := range tiers {
+		known[label] = struct{}{}
 	}
-	return nil
+	for _, label := range current {
+		if _, isTier := known[label]; !isTier {
+			continue
+		}
+		if label == target {
+			has = true
+			continue
+		}
+		stale = append(stale, label)
+	}
+	return stale, has
 }
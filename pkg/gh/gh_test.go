@@ -2,9 +2,11 @@ package gh_test
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
@@ -105,10 +107,10 @@ func TestGetPullRequestChangedLines(t *testing.T) {
 				github.NewClient(client),
 				"kkohtaka",
 				"gh-actions-pr-size",
-				42,
+				&github.PullRequest{Number: github.Int(42)},
 			)
 			require.NoError(t, err)
-			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, got.Total())
 		})
 	}
 }
@@ -130,29 +132,228 @@ func TestGetPullRequestChangedLinesReturnsError(t *testing.T) {
 		github.NewClient(client),
 		"kkohtaka",
 		"gh-actions-pr-size",
-		42,
+		&github.PullRequest{Number: github.Int(42)},
 	)
 	assert.ErrorContains(t, err, "get all commit files: list commit files: ")
-	assert.Zero(t, got)
+	assert.Nil(t, got)
+}
+
+func TestGetPullRequestChangedLinesBeyondListedFiles(t *testing.T) {
+	tcs := []struct {
+		name string
+		pr   *github.PullRequest
+	}{
+		{
+			name: "The pull request has totals of changes.",
+			pr: &github.PullRequest{
+				Number:       github.Int(42),
+				Additions:    github.Int(4000),
+				Deletions:    github.Int(0),
+				ChangedFiles: github.Int(3500),
+			},
+		},
+		{
+			name: "The pull request doesn't have totals of changes.",
+			pr:   &github.PullRequest{Number: github.Int(42)},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			const pages = gh.MaxListedFiles / 100
+			const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42"
+			httpmock.RegisterResponder(
+				"GET",
+				baseURL+"/files",
+				func(req *http.Request) (*http.Response, error) {
+					page := getPage(req)
+					files := make([]*github.CommitFile, 0, 100)
+					for i := 0; i < 100; i++ {
+						files = append(files, &github.CommitFile{
+							Filename:  github.String(fmt.Sprintf("file-%d-%d.go", page, i)),
+							Additions: github.Int(1),
+						})
+					}
+					if page == 1 {
+						files[0] = &github.CommitFile{Filename: github.String("go.sum"), Additions: github.Int(10)}
+					}
+					resp, err := httpmock.NewJsonResponse(200, files)
+					if err != nil {
+						return nil, err
+					}
+					resp.Header.Set("Link", generateLinkHeaderValue(baseURL+"/files", page, pages))
+					return resp, nil
+				},
+			)
+			httpmock.RegisterResponder(
+				"GET",
+				baseURL,
+				httpmock.NewJsonResponderOrPanic(200, &github.PullRequest{
+					Number:       github.Int(42),
+					Additions:    github.Int(4000),
+					Deletions:    github.Int(0),
+					ChangedFiles: github.Int(3500),
+				}),
+			)
+
+			filter, err := gh.NewGlobFilter(nil, []string{"go.sum"})
+			require.NoError(t, err)
+			got, err := gh.GetPullRequestChangedLines(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				tt.pr,
+				filter,
+			)
+			require.NoError(t, err)
+			assert.True(t, got.Truncated)
+			assert.Equal(t, 500, got.Unlisted)
+			assert.Equal(t, 3499, got.FileCount())
+			assert.Equal(t, 3990, got.Total())
+			assert.Equal(t, 10, got.ExcludedLines())
+		})
+	}
+}
+
+func TestPullRequestTotals(t *testing.T) {
+	got, ok := gh.PullRequestTotals(&github.PullRequest{
+		Additions:    github.Int(10),
+		Deletions:    github.Int(5),
+		ChangedFiles: github.Int(3),
+	})
+	require.True(t, ok)
+	assert.Equal(t, 15, got.Total())
+	assert.Equal(t, 3, got.FileCount())
+	assert.False(t, got.Truncated)
+
+	_, ok = gh.PullRequestTotals(&github.PullRequest{Number: github.Int(42)})
+	assert.False(t, ok)
+}
+
+func TestGetPullRequestChangedLinesWithLinguistFilter(t *testing.T) {
+	tcs := []struct {
+		name          string
+		treeTruncated bool
+	}{
+		{
+			name: "The tree of the repository is listed.",
+		},
+		{
+			name:          "The tree of the repository is truncated.",
+			treeTruncated: true,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			const repoURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size"
+			httpmock.RegisterResponder(
+				"GET",
+				repoURL+"/pulls/42/files",
+				httpmock.NewJsonResponderOrPanic(200, []*github.CommitFile{
+					{Filename: github.String("main.go"), Additions: github.Int(1), Deletions: github.Int(2)},
+					{Filename: github.String("api/v1/api.pb.go"), Additions: github.Int(100)},
+					{Filename: github.String("api/v1/api.go"), Additions: github.Int(10)},
+					{Filename: github.String("vendor/lib/lib.go"), Deletions: github.Int(200)},
+				}),
+			)
+			httpmock.RegisterResponder(
+				"GET",
+				repoURL+"/git/trees/abc",
+				httpmock.NewJsonResponderOrPanic(200, &github.Tree{
+					Entries: []github.TreeEntry{
+						{Path: github.String(".gitattributes"), Type: github.String("blob")},
+						{Path: github.String("api"), Type: github.String("tree")},
+						{Path: github.String("api/.gitattributes"), Type: github.String("blob")},
+					},
+					Truncated: github.Bool(tt.treeTruncated),
+				}),
+			)
+			gitattributes := map[string]string{
+				".gitattributes":     "vendor/** linguist-vendored\n",
+				"api/.gitattributes": "*.pb.go linguist-generated=true\n",
+			}
+			var requested []string
+			httpmock.RegisterResponder(
+				"GET",
+				fmt.Sprintf("=~^%s/contents/([^?]*)", repoURL),
+				func(req *http.Request) (*http.Response, error) {
+					p, err := httpmock.GetSubmatch(req, 1)
+					if err != nil {
+						return nil, err
+					}
+					requested = append(requested, p)
+					if ref := req.URL.Query().Get("ref"); ref != "abc" {
+						return httpmock.NewStringResponse(400, fmt.Sprintf("unexpected ref %q", ref)), nil
+					}
+					content, ok := gitattributes[p]
+					if !ok {
+						return httpmock.NewStringResponse(404, `{"message": "Not Found"}`), nil
+					}
+					return httpmock.NewJsonResponse(200, &github.RepositoryContent{
+						Type:     github.String("file"),
+						Encoding: github.String("base64"),
+						Content:  github.String(base64.StdEncoding.EncodeToString([]byte(content))),
+					})
+				},
+			)
+
+			linguist := gh.NewLinguistFilter(github.NewClient(client), "kkohtaka", "gh-actions-pr-size", "abc")
+			empty, err := linguist.Empty(context.Background())
+			require.NoError(t, err)
+			assert.False(t, empty)
+
+			got, err := gh.GetPullRequestChangedLines(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				&github.PullRequest{Number: github.Int(42)},
+				linguist,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, 13, got.Total())
+			assert.Equal(t, 300, got.ExcludedLines())
+			require.Len(t, got.Excluded, 2)
+			assert.Equal(t, "linguist-generated", got.Excluded[0].Reason)
+			assert.Equal(t, "linguist-vendored", got.Excluded[1].Reason)
+			if !tt.treeTruncated {
+				assert.Equal(t, []string{".gitattributes", "api/.gitattributes"}, requested)
+			}
+		})
+	}
 }
 
 func TestSetLabelOnPullRequest(t *testing.T) {
 	tcs := []struct {
 		name   string
 		labels [][]*github.Label
-		size   gh.Size
+		tiers  []gh.Tier
+		size   gh.Tier
+		// added is labels returned after adding a label, which may contain ones added concurrently.  If nil, the current
+		// labels and the added one are returned.
+		added []string
+		// gone is labels which were removed concurrently, so that removing them fails with 404 Not Found.
+		gone []string
 
-		wantDeletedLabels []string
 		wantCreatedLabels []string
+		wantRemovedLabels []string
 	}{
 		{
 			name: "The pull request doesn't have labels.",
 			labels: [][]*github.Label{
 				{},
 			},
-			size: gh.SizeXL,
+			size: tier("XL"),
 			wantCreatedLabels: []string{
-				gh.SizeXL.GetLabel(),
+				"size/XL",
 			},
 		},
 		{
@@ -160,16 +361,16 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 			labels: [][]*github.Label{
 				{
 					{
-						Name: github.String(gh.SizeL.GetLabel()),
+						Name: github.String("size/L"),
 					},
 				},
 			},
-			size: gh.SizeXL,
-			wantDeletedLabels: []string{
-				gh.SizeL.GetLabel(),
-			},
+			size: tier("XL"),
 			wantCreatedLabels: []string{
-				gh.SizeXL.GetLabel(),
+				"size/XL",
+			},
+			wantRemovedLabels: []string{
+				"size/L",
 			},
 		},
 		{
@@ -177,11 +378,14 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 			labels: [][]*github.Label{
 				{
 					{
-						Name: github.String(gh.SizeXL.GetLabel()),
+						Name: github.String("foo"),
+					},
+					{
+						Name: github.String("size/XL"),
 					},
 				},
 			},
-			size: gh.SizeXL,
+			size: tier("XL"),
 		},
 		{
 			name: "The pull request has another size label and non-size labels.",
@@ -196,7 +400,7 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 						Name: github.String("bar"),
 					},
 					{
-						Name: github.String(gh.SizeS.GetLabel()),
+						Name: github.String("size/S"),
 					},
 					{
 						Name: github.String("baz"),
@@ -208,51 +412,127 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 					},
 				},
 			},
-			size: gh.SizeM,
-			wantDeletedLabels: []string{
-				gh.SizeS.GetLabel(),
+			size: tier("M"),
+			wantCreatedLabels: []string{
+				"size/M",
+			},
+			wantRemovedLabels: []string{
+				"size/S",
+			},
+		},
+		{
+			name: "The pull request has multiple size labels including the target one.",
+			labels: [][]*github.Label{
+				{
+					{
+						Name: github.String("size/L"),
+					},
+					{
+						Name: github.String("size/XL"),
+					},
+					{
+						Name: github.String("size/M"),
+					},
+				},
+			},
+			size: tier("XL"),
+			wantRemovedLabels: []string{
+				"size/L",
+				"size/M",
 			},
+		},
+		{
+			name: "Another size label is added concurrently.",
+			labels: [][]*github.Label{
+				{
+					{
+						Name: github.String("foo"),
+					},
+				},
+			},
+			size:  tier("XL"),
+			added: []string{"foo", "size/M", "size/XL"},
 			wantCreatedLabels: []string{
-				gh.SizeM.GetLabel(),
+				"size/XL",
+			},
+			wantRemovedLabels: []string{
+				"size/M",
 			},
 		},
 		{
-			name: "The pull request has multiple size labels.",
+			name: "The pull request has labels which look like size labels but don't belong to the tiers.",
 			labels: [][]*github.Label{
 				{
 					{
-						Name: github.String(gh.SizeL.GetLabel()),
+						Name: github.String("size/L"),
 					},
 					{
-						Name: github.String(gh.SizeM.GetLabel()),
+						Name: github.String("pr-size: S"),
 					},
 				},
 			},
-			size: gh.SizeXL,
-			wantDeletedLabels: []string{
-				gh.SizeL.GetLabel(),
-				gh.SizeM.GetLabel(),
+			tiers: []gh.Tier{
+				{Name: "S", Max: 49, Label: "pr-size: S"},
+				{Name: "L", Max: gh.Unbounded, Label: "pr-size: L"},
+			},
+			size: gh.Tier{Name: "L", Max: gh.Unbounded, Label: "pr-size: L"},
+			wantCreatedLabels: []string{
+				"pr-size: L",
+			},
+			wantRemovedLabels: []string{
+				"pr-size: S",
 			},
+		},
+		{
+			name: "A stale size label is removed concurrently.",
+			labels: [][]*github.Label{
+				{
+					{
+						Name: github.String("size/S"),
+					},
+				},
+			},
+			size: tier("M"),
+			gone: []string{"size/S"},
 			wantCreatedLabels: []string{
-				gh.SizeXL.GetLabel(),
+				"size/M",
+			},
+			wantRemovedLabels: []string{
+				"size/S",
 			},
 		},
 	}
 	for _, tt := range tcs {
 		t.Run(tt.name, func(t *testing.T) {
+			sizer := gh.DefaultSizer()
+			if tt.tiers != nil {
+				var err error
+				sizer, err = gh.NewSizer(tt.tiers)
+				require.NoError(t, err)
+			}
+
 			client := &http.Client{}
 			httpmock.ActivateNonDefault(client)
 			defer httpmock.DeactivateAndReset()
 
 			var (
-				gotDeletedLabels []string
 				gotCreatedLabels []string
+				gotRemovedLabels []string
+				// state is the labels on the pull request, which are listed in a single page once they're changed.
+				state   []*github.Label
+				changed bool
 			)
+			for _, page := range tt.labels {
+				state = append(state, page...)
+			}
 			const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels"
 			httpmock.RegisterResponder(
 				"GET",
 				baseURL,
 				func(req *http.Request) (*http.Response, error) {
+					if changed {
+						return httpmock.NewJsonResponse(200, state)
+					}
 					page := getPage(req)
 					if page-1 >= len(tt.labels) {
 						return nil, fmt.Errorf("invalid query value")
@@ -270,14 +550,24 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 				"DELETE",
 				fmt.Sprintf("=~^%s/(.*)$", baseURL),
 				func(req *http.Request) (*http.Response, error) {
-					label, err := httpmock.GetSubmatch(req, 1)
+					label, err := url.PathUnescape(httpmock.MustGetSubmatch(req, 1))
 					if err != nil {
-						return httpmock.NewStringResponse(400, "unable to get a label name"), nil
+						return nil, err
 					}
-					gotDeletedLabels = append(gotDeletedLabels, label)
-					resp := httpmock.NewBytesResponse(200, nil)
-					resp.Header.Set("Content-Type", "application/json")
-					return resp, nil
+					gotRemovedLabels = append(gotRemovedLabels, label)
+					for _, gone := range tt.gone {
+						if label == gone {
+							return httpmock.NewStringResponse(404, `{"message": "Label does not exist"}`), nil
+						}
+					}
+					var rest []*github.Label
+					for _, l := range state {
+						if l.GetName() != label {
+							rest = append(rest, l)
+						}
+					}
+					state, changed = rest, true
+					return httpmock.NewJsonResponse(200, state)
 				},
 			)
 			httpmock.RegisterResponder(
@@ -292,11 +582,27 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 						), nil
 					}
 					gotCreatedLabels = append(gotCreatedLabels, labels...)
-					resp := httpmock.NewBytesResponse(200, nil)
-					resp.Header.Set("Content-Type", "application/json")
-					return resp, nil
+					added := tt.added
+					if added == nil {
+						for _, page := range tt.labels {
+							for _, label := range page {
+								added = append(added, label.GetName())
+							}
+						}
+						added = append(added, labels...)
+					}
+					state, changed = nil, true
+					for _, label := range added {
+						state = append(state, &github.Label{Name: github.String(label)})
+					}
+					return httpmock.NewJsonResponse(200, state)
 				},
 			)
+			httpmock.RegisterResponder(
+				"PUT",
+				baseURL,
+				httpmock.NewErrorResponder(fmt.Errorf("labels must not be replaced at once")),
+			)
 
 			err := gh.SetLabelOnPullRequest(
 				context.Background(),
@@ -304,16 +610,88 @@ func TestSetLabelOnPullRequest(t *testing.T) {
 				"kkohtaka",
 				"gh-actions-pr-size",
 				42,
+				sizer,
 				tt.size,
 			)
 			require.NoError(t, err)
 
-			assert.Equal(t, tt.wantDeletedLabels, gotDeletedLabels)
 			assert.Equal(t, tt.wantCreatedLabels, gotCreatedLabels)
+			assert.Equal(t, tt.wantRemovedLabels, gotRemovedLabels)
 		})
 	}
 }
 
+func TestSetLabelOnPullRequestConcurrently(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	// This run labels the pull request with size/L while another run labels it with size/XL.  Both runs add their
+	// labels first, and then remove the label of each other as a stale one.
+	state := []string{"size/S"}
+	var added []string
+	var labels = func() []*github.Label {
+		var res []*github.Label
+		for _, label := range state {
+			res = append(res, &github.Label{Name: github.String(label)})
+		}
+		return res
+	}
+	var remove = func(label string) {
+		var rest []string
+		for _, l := range state {
+			if l != label {
+				rest = append(rest, l)
+			}
+		}
+		state = rest
+	}
+	const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels"
+	httpmock.RegisterResponder("GET", baseURL, func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewJsonResponse(200, labels())
+	})
+	httpmock.RegisterResponder("POST", baseURL, func(req *http.Request) (*http.Response, error) {
+		var posted []string
+		if err := json.NewDecoder(req.Body).Decode(&posted); err != nil {
+			return nil, err
+		}
+		added = append(added, posted...)
+		state = append(state, posted...)
+		if len(added) == 1 {
+			// The other run adds its label.
+			state = append(state, "size/XL")
+		}
+		return httpmock.NewJsonResponse(200, labels())
+	})
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("=~^%s/(.*)$", baseURL), func(req *http.Request) (
+		*http.Response, error,
+	) {
+		label, err := url.PathUnescape(httpmock.MustGetSubmatch(req, 1))
+		if err != nil {
+			return nil, err
+		}
+		remove(label)
+		if label == "size/XL" {
+			// The other run removes the label of this run as a stale one.
+			remove("size/L")
+		}
+		return httpmock.NewJsonResponse(200, labels())
+	})
+
+	err := gh.SetLabelOnPullRequest(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		42,
+		gh.DefaultSizer(),
+		tier("L"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"size/L", "size/L"}, added)
+	assert.Equal(t, []string{"size/L"}, state)
+}
+
 func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 	const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels"
 
@@ -336,14 +714,15 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 				"kkohtaka",
 				"gh-actions-pr-size",
 				42,
-				gh.SizeXL,
+				gh.DefaultSizer(),
+				tier("XL"),
 			)
 			assert.ErrorContains(t, err, "list labels by issue: ")
 		},
 	)
 
 	t.Run(
-		"GitHub API that deleting an issue label returns an error.",
+		"GitHub API that removing an issue label returns an error.",
 		func(t *testing.T) {
 			client := &http.Client{}
 			httpmock.ActivateNonDefault(client)
@@ -355,7 +734,10 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 				func(req *http.Request) (*http.Response, error) {
 					resp, err := httpmock.NewJsonResponse(200, []*github.Label{
 						{
-							Name: github.String(gh.SizeL.GetLabel()),
+							Name: github.String("size/L"),
+						},
+						{
+							Name: github.String("size/XL"),
 						},
 					})
 					if err != nil {
@@ -368,7 +750,7 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 			)
 			httpmock.RegisterResponder(
 				"DELETE",
-				fmt.Sprintf("%s/%s", baseURL, gh.SizeL.GetLabel()),
+				baseURL+"/size%2FL",
 				httpmock.NewErrorResponder(fmt.Errorf("test for error handling")),
 			)
 
@@ -378,7 +760,8 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 				"kkohtaka",
 				"gh-actions-pr-size",
 				42,
-				gh.SizeXL,
+				gh.DefaultSizer(),
+				tier("XL"),
 			)
 			assert.ErrorContains(t, err, "remove a label from a pull request: ")
 		},
@@ -395,11 +778,7 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 				"GET",
 				baseURL,
 				func(req *http.Request) (*http.Response, error) {
-					resp, err := httpmock.NewJsonResponse(200, []*github.Label{
-						{
-							Name: github.String(gh.SizeL.GetLabel()),
-						},
-					})
+					resp, err := httpmock.NewJsonResponse(200, []*github.Label{})
 					if err != nil {
 						return nil, err
 					}
@@ -408,11 +787,6 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 					return resp, nil
 				},
 			)
-			httpmock.RegisterResponder(
-				"DELETE",
-				fmt.Sprintf("%s/%s", baseURL, gh.SizeL.GetLabel()),
-				httpmock.NewBytesResponder(200, nil),
-			)
 			httpmock.RegisterResponder(
 				"POST",
 				baseURL,
@@ -425,7 +799,8 @@ func TestSetLabelOnPullRequestReturnsError(t *testing.T) {
 				"kkohtaka",
 				"gh-actions-pr-size",
 				42,
-				gh.SizeXL,
+				gh.DefaultSizer(),
+				tier("XL"),
 			)
 			assert.ErrorContains(t, err, "add a label to a pull request: ")
 		},
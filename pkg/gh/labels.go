@@ -0,0 +1,93 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v29/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// LabelChange is a change applied to a label of a tier in a repository.
+type LabelChange struct {
+	// Label is a name of the label.
+	Label string
+	// Action is one of "created", "updated" and "unchanged".
+	Action string
+}
+
+// SyncLabels ensures that labels of the tiers exist in the repository with their colors and descriptions.  Existing
+// labels with stale colors or descriptions are updated.  Tiers without colors or descriptions keep the ones of the
+// existing labels.
+func SyncLabels(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+	tiers []Tier,
+) ([]LabelChange, error) {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+	)
+
+	existing := make(map[string]*github.Label)
+	for offset := 0; ; offset++ {
+		labels, resp, err := client.Issues.ListLabels(
+			ctx,
+			owner, repo,
+			&github.ListOptions{Page: offset + 1, PerPage: 100},
+		)
+		if err != nil {
+			logger.Error(err, "Failed to list labels in a repository")
+			return nil, fmt.Errorf("list labels: %w", err)
+		}
+		for _, label := range labels {
+			// Label names are case-insensitive.
+			existing[strings.ToLower(label.GetName())] = label
+		}
+		if offset+1 >= resp.LastPage {
+			break
+		}
+	}
+
+	var changes []LabelChange
+	for _, tier := range tiers {
+		newLogger := logger.WithValues("label", tier.Label)
+		label := &github.Label{Name: github.String(tier.Label)}
+		if tier.Color != "" {
+			label.Color = github.String(tier.Color)
+		}
+		if tier.Description != "" {
+			label.Description = github.String(tier.Description)
+		}
+
+		current, ok := existing[strings.ToLower(tier.Label)]
+		if !ok {
+			if _, _, err := client.Issues.CreateLabel(ctx, owner, repo, label); err != nil {
+				newLogger.Error(err, "Failed to create a label in a repository")
+				return nil, fmt.Errorf("create a label %q: %w", tier.Label, err)
+			}
+			newLogger.Info("A label was created in the repository")
+			changes = append(changes, LabelChange{Label: tier.Label, Action: "created"})
+			continue
+		}
+
+		if current.GetName() == tier.Label &&
+			(tier.Color == "" || strings.EqualFold(current.GetColor(), tier.Color)) &&
+			(tier.Description == "" || current.GetDescription() == tier.Description) {
+			changes = append(changes, LabelChange{Label: tier.Label, Action: "unchanged"})
+			continue
+		}
+		// go-github doesn't escape the name in the path, while names of labels of tiers contain "/" by default.
+		name := url.PathEscape(current.GetName())
+		if _, _, err := client.Issues.EditLabel(ctx, owner, repo, name, label); err != nil {
+			newLogger.Error(err, "Failed to update a label in a repository")
+			return nil, fmt.Errorf("update a label %q: %w", tier.Label, err)
+		}
+		newLogger.Info("A label was updated in the repository")
+		changes = append(changes, LabelChange{Label: tier.Label, Action: "updated"})
+	}
+	return changes, nil
+}
@@ -0,0 +1,122 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncLabels(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/labels"
+	pages := [][]*github.Label{
+		{
+			{Name: github.String("bug"), Color: github.String("ff0000")},
+			{Name: github.String("size/S"), Color: github.String("00FF00"), Description: github.String("0-99 changed lines")},
+		},
+		{
+			{Name: github.String("size/m"), Color: github.String("ededed")},
+		},
+	}
+	httpmock.RegisterResponder(
+		"GET",
+		baseURL,
+		func(req *http.Request) (*http.Response, error) {
+			page := getPage(req)
+			resp, err := httpmock.NewJsonResponse(200, pages[page-1])
+			if err != nil {
+				return nil, err
+			}
+			resp.Header.Set("Link", generateLinkHeaderValue(baseURL, page, len(pages)))
+			return resp, nil
+		},
+	)
+	var created []github.Label
+	httpmock.RegisterResponder(
+		"POST",
+		baseURL,
+		func(req *http.Request) (*http.Response, error) {
+			var label github.Label
+			if err := json.NewDecoder(req.Body).Decode(&label); err != nil {
+				return nil, err
+			}
+			created = append(created, label)
+			return httpmock.NewJsonResponse(201, label)
+		},
+	)
+	var edited []github.Label
+	httpmock.RegisterResponder(
+		"PATCH",
+		baseURL+"/size%2Fm",
+		func(req *http.Request) (*http.Response, error) {
+			var label github.Label
+			if err := json.NewDecoder(req.Body).Decode(&label); err != nil {
+				return nil, err
+			}
+			edited = append(edited, label)
+			return httpmock.NewJsonResponse(200, label)
+		},
+	)
+
+	got, err := gh.SyncLabels(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		[]gh.Tier{
+			{Name: "S", Max: 99, Label: "size/S", Color: "00ff00", Description: "0-99 changed lines"},
+			{Name: "M", Max: 499, Label: "size/M", Color: "ffff00", Description: "100-499 changed lines"},
+			{Name: "L", Max: gh.Unbounded, Label: "size/L", Color: "ff0000", Description: "500 or more changed lines"},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []gh.LabelChange{
+		{Label: "size/S", Action: "unchanged"},
+		{Label: "size/M", Action: "updated"},
+		{Label: "size/L", Action: "created"},
+	}, got)
+	assert.Equal(t, []github.Label{
+		{
+			Name:        github.String("size/L"),
+			Color:       github.String("ff0000"),
+			Description: github.String("500 or more changed lines"),
+		},
+	}, created)
+	assert.Equal(t, []github.Label{
+		{
+			Name:        github.String("size/M"),
+			Color:       github.String("ffff00"),
+			Description: github.String("100-499 changed lines"),
+		},
+	}, edited)
+}
+
+func TestSyncLabelsReturnsError(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	const baseURL = "https://api.github.com/repos/kkohtaka/gh-actions-pr-size/labels"
+	httpmock.RegisterResponder("GET", baseURL, httpmock.NewJsonResponderOrPanic(200, []*github.Label{}))
+	httpmock.RegisterResponder("POST", baseURL, httpmock.NewErrorResponder(fmt.Errorf("test for error handling")))
+
+	_, err := gh.SyncLabels(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		[]gh.Tier{{Name: "L", Max: gh.Unbounded, Label: "size/L"}},
+	)
+	assert.ErrorContains(t, err, `create a label "size/L": `)
+}
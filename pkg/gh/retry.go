@@ -0,0 +1,288 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+	headerRetryAfter    = "Retry-After"
+
+	// DefaultMaxRetries is the default number of times a request is retried.
+	DefaultMaxRetries = 3
+	// DefaultMaxWait is the default maximum wait before a request is sent or retried, which is shorter than a reset of
+	// the primary rate limit, which can take up to an hour.
+	DefaultMaxWait = 10 * time.Minute
+	// defaultBackoff is the initial wait before retrying a request failed with a server error, which is doubled on
+	// every retry.
+	defaultBackoff = time.Second
+	// maxBackoff is the maximum wait before retrying a request failed with a server error.
+	maxBackoff = 30 * time.Second
+	// secondaryRateLimitWait is the wait before retrying a request exceeding a secondary rate limit without
+	// Retry-After, which GitHub recommends to be at least one minute.
+	secondaryRateLimitWait = time.Minute
+	// lowQuotaRatio is the ratio of remaining requests to the rate limit below which the quota is logged.
+	lowQuotaRatio = 0.1
+)
+
+// RetryTransport is an http.RoundTripper which retries requests to GitHub API failed by rate limits or server errors.
+//
+//   - A request exceeding the primary rate limit is retried after X-RateLimit-Reset.
+//   - A request exceeding a secondary rate limit is retried after Retry-After, or a minute if it's not specified.
+//   - An idempotent request failed with a server error is retried with an exponential backoff with jitter.
+//
+// A POST request creating a resource, e.g. a comment or a check run, isn't retried after a server error since it may
+// have taken effect, though it's retried after a rate limit, which rejects the request before it takes effect.
+//
+// A request is not retried if the wait would exceed MaxWait or the deadline of its context, in which case the failed
+// response is returned as it is, so that go-github reports it as *github.RateLimitError, *github.AbuseRateLimitError or
+// *github.ErrorResponse.
+type RetryTransport struct {
+	// Base is the underlying transport.  If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// MaxRetries is the maximum number of times a request is retried.
+	MaxRetries int
+	// Backoff is the initial wait before retrying a request failed with a server error.
+	Backoff time.Duration
+	// MaxWait is the maximum wait before a request is sent or retried.  If zero, waits are bounded only by the deadline
+	// of the context.
+	MaxWait time.Duration
+
+	mu sync.Mutex
+	// resetAt is when the primary rate limit is reset after it's exhausted, or zero if requests remain.
+	resetAt time.Time
+}
+
+var _ http.RoundTripper = &RetryTransport{}
+
+// NewRetryTransport returns a transport which retries requests sent by the base transport, waiting at most
+// DefaultMaxWait each time.
+func NewRetryTransport(base http.RoundTripper, maxRetries int) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		MaxRetries: maxRetries,
+		Backoff:    defaultBackoff,
+		MaxWait:    DefaultMaxWait,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	logger := log.FromContext(ctx).WithValues("method", req.Method, "url", req.URL.Redacted())
+
+	// If the reset is too far, the request is sent anyway, and fails with the primary rate limit.
+	if wait := t.untilReset(); wait > 0 && !t.exceedsLimit(ctx, wait) {
+		logger.Info("Waiting for the rate limit of GitHub API to be reset", "wait", wait.String())
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			var err error
+			if r, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.base().RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		t.observe(ctx, resp)
+
+		wait, reason := t.retryWait(resp, attempt)
+		if reason == "" {
+			return resp, nil
+		}
+		logger := logger.WithValues("status", resp.StatusCode, "reason", reason, "attempt", attempt+1)
+		switch {
+		case attempt >= t.MaxRetries:
+			logger.Info("Gave up retrying a request to GitHub API")
+			return resp, nil
+		case resp.StatusCode >= http.StatusInternalServerError && !idempotent(req):
+			logger.Info("A request to GitHub API isn't retried after a server error since it may have taken effect")
+			return resp, nil
+		case req.Body != nil && req.GetBody == nil:
+			logger.Info("A request to GitHub API can't be retried since its body can't be rewound")
+			return resp, nil
+		case t.exceedsLimit(ctx, wait):
+			logger.Info("A request to GitHub API isn't retried since the wait exceeds the limit",
+				"wait", wait.String(),
+			)
+			return resp, nil
+		}
+
+		logger.Info("Retrying a request to GitHub API", "wait", wait.String())
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// untilReset returns the wait until the primary rate limit is reset if it's exhausted.
+func (t *RetryTransport) untilReset() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Until(t.resetAt)
+}
+
+// observe records the primary rate limit reported by the response, and logs the quota if it's running low.
+//
+// Once a response reports no remaining requests, go-github refuses further requests by itself with
+// *github.RateLimitError until the reset, which would fail the job.  The transport waits for the reset before the next
+// request instead, and hides X-RateLimit-Reset of successful responses from go-github.
+func (t *RetryTransport) observe(ctx context.Context, resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateRemaining))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get(headerRateLimit))
+	reset := parseUnixTime(resp.Header.Get(headerRateReset))
+	if float64(remaining) < float64(limit)*lowQuotaRatio {
+		log.FromContext(ctx).Info("The quota of GitHub API is running low",
+			"remaining", remaining,
+			"limit", limit,
+			"reset", reset,
+		)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if remaining > 0 {
+		t.resetAt = time.Time{}
+		return
+	}
+	t.resetAt = reset
+	if resp.StatusCode < http.StatusBadRequest {
+		resp.Header.Del(headerRateReset)
+	}
+}
+
+// retryWait returns a wait before retrying the request of the response and a reason of the retry, or an empty reason
+// if the request shouldn't be retried.
+func (t *RetryTransport) retryWait(resp *http.Response, attempt int) (time.Duration, string) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if resp.Header.Get(headerRateRemaining) == "0" {
+			// The reset is in seconds, so wait for one more second not to retry too early.
+			return time.Until(parseUnixTime(resp.Header.Get(headerRateReset))) + time.Second, "primary rate limit"
+		}
+		if v := resp.Header.Get(headerRetryAfter); v != "" {
+			seconds, err := strconv.Atoi(v)
+			if err == nil {
+				return time.Duration(seconds) * time.Second, "secondary rate limit"
+			}
+		}
+		if isSecondaryRateLimit(resp) {
+			return secondaryRateLimitWait, "secondary rate limit"
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		backoff := t.Backoff << attempt
+		if backoff > maxBackoff || backoff < t.Backoff {
+			backoff = maxBackoff
+		}
+		// Wait for a random duration in [backoff/2, backoff) so that concurrent requests don't retry at once.
+		return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), "server error"
+	}
+	return 0, ""
+}
+
+// issueLabelsPath matches a path of the API adding labels to an issue or a pull request.
+var issueLabelsPath = regexp.MustCompile(`/repos/[^/]+/[^/]+/issues/\d+/labels$`)
+
+// idempotent reports whether sending the request more than once has the same effect as sending it once.  Adding labels
+// is idempotent though its method is POST.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return issueLabelsPath.MatchString(req.URL.Path)
+	}
+	return false
+}
+
+// isSecondaryRateLimit reports whether the response tells that a secondary rate limit (formerly called an abuse rate
+// limit) is exceeded.  The body of the response is restored after being read.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	body := strings.ToLower(string(data))
+	return strings.Contains(body, "secondary rate limit") || strings.Contains(body, "abuse")
+}
+
+// rewind returns a copy of the request whose body is read from the beginning.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// parseUnixTime parses a value of X-RateLimit-Reset, which is seconds since the epoch.  It returns zero if the value is
+// malformed.
+func parseUnixTime(v string) time.Time {
+	seconds, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// exceedsLimit reports whether waiting for d exceeds MaxWait or the deadline of the context.
+func (t *RetryTransport) exceedsLimit(ctx context.Context, d time.Duration) bool {
+	if t.MaxWait > 0 && d > t.MaxWait {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && time.Now().Add(d).After(deadline)
+}
+
+// sleep waits for d or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,343 @@
+package gh_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// response is a response returned by a mock of GitHub API.
+type response struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+func TestRetryTransport(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	ok := response{status: 200, body: `[]`}
+
+	tcs := []struct {
+		name      string
+		responses []response
+		timeout   time.Duration
+
+		wantCalls int
+		wantErr   func(t *testing.T, err error)
+	}{
+		{
+			name:      "A request succeeds.",
+			responses: []response{ok},
+			wantCalls: 1,
+		},
+		{
+			name: "A request fails with a server error once.",
+			responses: []response{
+				{status: 502, body: `{"message": "Bad Gateway"}`},
+				ok,
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "A request keeps failing with server errors.",
+			responses: []response{
+				{status: 500, body: `{"message": "Server Error"}`},
+				{status: 500, body: `{"message": "Server Error"}`},
+				{status: 500, body: `{"message": "Server Error"}`},
+				{status: 500, body: `{"message": "Server Error"}`},
+			},
+			wantCalls: 4,
+			wantErr: func(t *testing.T, err error) {
+				var e *github.ErrorResponse
+				require.ErrorAs(t, err, &e)
+				assert.Equal(t, 500, e.Response.StatusCode)
+			},
+		},
+		{
+			name: "A request exceeds the primary rate limit.",
+			responses: []response{
+				{
+					status: 403,
+					header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+					body:   `{"message": "API rate limit exceeded"}`,
+				},
+				ok,
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "A request exceeds a secondary rate limit with Retry-After.",
+			responses: []response{
+				{
+					status: 403,
+					header: map[string]string{"Retry-After": "0"},
+					body:   `{"message": "You have exceeded a secondary rate limit."}`,
+				},
+				ok,
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "A wait for the primary rate limit exceeds the deadline.",
+			responses: []response{
+				{
+					status: 403,
+					header: map[string]string{
+						"X-RateLimit-Remaining": "0",
+						"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+					},
+					body: `{"message": "API rate limit exceeded"}`,
+				},
+			},
+			timeout:   time.Minute,
+			wantCalls: 1,
+			wantErr: func(t *testing.T, err error) {
+				var e *github.RateLimitError
+				assert.ErrorAs(t, err, &e)
+			},
+		},
+		{
+			name: "A wait for the primary rate limit exceeds the maximum wait.",
+			responses: []response{
+				{
+					status: 403,
+					header: map[string]string{
+						"X-RateLimit-Remaining": "0",
+						"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+					},
+					body: `{"message": "API rate limit exceeded"}`,
+				},
+			},
+			wantCalls: 1,
+			wantErr: func(t *testing.T, err error) {
+				var e *github.RateLimitError
+				assert.ErrorAs(t, err, &e)
+			},
+		},
+		{
+			name: "A wait for a secondary rate limit without Retry-After exceeds the deadline.",
+			responses: []response{
+				{
+					status: 403,
+					body: `{"message": "You have triggered an abuse detection mechanism.", ` +
+						`"documentation_url": "https://developer.github.com/v3/#abuse-rate-limits"}`,
+				},
+			},
+			timeout:   time.Second,
+			wantCalls: 1,
+			wantErr: func(t *testing.T, err error) {
+				var e *github.AbuseRateLimitError
+				assert.ErrorAs(t, err, &e)
+			},
+		},
+		{
+			name: "A request is forbidden without rate limits.",
+			responses: []response{
+				{status: 403, body: `{"message": "Resource not accessible by integration"}`},
+			},
+			wantCalls: 1,
+			wantErr: func(t *testing.T, err error) {
+				var e *github.ErrorResponse
+				require.ErrorAs(t, err, &e)
+				assert.Equal(t, 403, e.Response.StatusCode)
+			},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.NewMockTransport()
+			var calls int
+			var bodies []string
+			mock.RegisterResponder(
+				"POST",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/labels",
+				func(req *http.Request) (*http.Response, error) {
+					body, err := io.ReadAll(req.Body)
+					if err != nil {
+						return nil, err
+					}
+					bodies = append(bodies, string(body))
+					r := tt.responses[calls]
+					calls++
+					resp := httpmock.NewStringResponse(r.status, r.body)
+					for k, v := range r.header {
+						resp.Header.Set(k, v)
+					}
+					return resp, nil
+				},
+			)
+			transport := gh.NewRetryTransport(mock, gh.DefaultMaxRetries)
+			transport.Backoff = time.Millisecond
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			client := github.NewClient(&http.Client{Transport: transport})
+			_, _, err := client.Issues.AddLabelsToIssue(ctx, "kkohtaka", "gh-actions-pr-size", 42, []string{"size/S"})
+			if tt.wantErr != nil {
+				tt.wantErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+			for _, body := range bodies {
+				assert.JSONEq(t, `["size/S"]`, body)
+			}
+		})
+	}
+}
+
+func TestRetryTransportCreatingComment(t *testing.T) {
+	tcs := []struct {
+		name      string
+		responses []response
+
+		wantCalls int
+	}{
+		{
+			name: "A request creating a comment isn't retried after a server error.",
+			responses: []response{
+				{status: 502, body: `{"message": "Bad Gateway"}`},
+				{status: 201, body: `{}`},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "A request creating a comment is retried after a secondary rate limit.",
+			responses: []response{
+				{
+					status: 403,
+					header: map[string]string{"Retry-After": "0"},
+					body:   `{"message": "You have exceeded a secondary rate limit."}`,
+				},
+				{status: 201, body: `{}`},
+			},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := httpmock.NewMockTransport()
+			var calls int
+			mock.RegisterResponder(
+				"POST",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/issues/42/comments",
+				func(req *http.Request) (*http.Response, error) {
+					r := tt.responses[calls]
+					calls++
+					resp := httpmock.NewStringResponse(r.status, r.body)
+					for k, v := range r.header {
+						resp.Header.Set(k, v)
+					}
+					return resp, nil
+				},
+			)
+			transport := gh.NewRetryTransport(mock, gh.DefaultMaxRetries)
+			transport.Backoff = time.Millisecond
+
+			client := github.NewClient(&http.Client{Transport: transport})
+			_, _, _ = client.Issues.CreateComment(
+				context.Background(), "kkohtaka", "gh-actions-pr-size", 42, &github.IssueComment{Body: github.String("x")},
+			)
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestRetryTransportWaitsForReset(t *testing.T) {
+	mock := httpmock.NewMockTransport()
+	var calls []time.Time
+	reset := time.Now().Add(time.Second).Truncate(time.Second)
+	mock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+		func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, time.Now())
+			resp := httpmock.NewStringResponse(200, `[]`)
+			remaining := "0"
+			if len(calls) > 1 {
+				remaining = "4999"
+			}
+			resp.Header.Set("X-RateLimit-Limit", "5000")
+			resp.Header.Set("X-RateLimit-Remaining", remaining)
+			resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			return resp, nil
+		},
+	)
+
+	client := github.NewClient(&http.Client{Transport: gh.NewRetryTransport(mock, gh.DefaultMaxRetries)})
+	for i := 0; i < 2; i++ {
+		// go-github would refuse the second request by itself without the transport since the first response reports
+		// no remaining requests.
+		_, _, err := client.PullRequests.ListFiles(context.Background(), "kkohtaka", "gh-actions-pr-size", 42, nil)
+		require.NoError(t, err)
+	}
+	require.Len(t, calls, 2)
+	assert.False(t, calls[1].Before(reset))
+}
+
+func TestRetryTransportDoesNotWaitBeyondMaxWait(t *testing.T) {
+	mock := httpmock.NewMockTransport()
+	var calls int
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	mock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+		func(req *http.Request) (*http.Response, error) {
+			calls++
+			status, body := 200, `[]`
+			if calls > 1 {
+				status, body = 403, `{"message": "API rate limit exceeded"}`
+			}
+			resp := httpmock.NewStringResponse(status, body)
+			resp.Header.Set("X-RateLimit-Limit", "5000")
+			resp.Header.Set("X-RateLimit-Remaining", "0")
+			resp.Header.Set("X-RateLimit-Reset", reset)
+			return resp, nil
+		},
+	)
+
+	client := github.NewClient(&http.Client{Transport: gh.NewRetryTransport(mock, gh.DefaultMaxRetries)})
+	_, _, err := client.PullRequests.ListFiles(context.Background(), "kkohtaka", "gh-actions-pr-size", 42, nil)
+	require.NoError(t, err)
+
+	// The second request is sent without waiting for the reset an hour later, and fails.
+	start := time.Now()
+	_, _, err = client.PullRequests.ListFiles(context.Background(), "kkohtaka", "gh-actions-pr-size", 42, nil)
+	var e *github.RateLimitError
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, 2, calls)
+	assert.Less(t, time.Since(start), time.Minute)
+}
+
+func TestRetryTransportReturnsError(t *testing.T) {
+	mock := httpmock.NewMockTransport()
+	mock.RegisterResponder(
+		"GET",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/pulls/42/files",
+		httpmock.NewStringResponder(503, `{"message": "Service Unavailable"}`),
+	)
+	transport := gh.NewRetryTransport(mock, gh.DefaultMaxRetries)
+	transport.Backoff = time.Hour
+
+	// The context is canceled while waiting for a retry.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	client := github.NewClient(&http.Client{Transport: transport})
+	_, _, err := client.PullRequests.ListFiles(ctx, "kkohtaka", "gh-actions-pr-size", 42, nil)
+	assert.True(t, errors.Is(err, context.Canceled), "unexpected error: %v", err)
+}
@@ -1,87 +1,131 @@
 package gh
 
-const (
-	labelPrefix = "size/"
-
-	labelXS      = "size/XS"
-	labelS       = "size/S"
-	labelM       = "size/M"
-	labelL       = "size/L"
-	labelXL      = "size/XL"
-	labelXXL     = "size/XXL"
-	labelUnknown = "size/?"
+import (
+	"errors"
+	"fmt"
+	"math"
 )
 
-const (
-	sizeThresholdS   = 10
-	sizeThresholdM   = 30
-	sizeThresholdL   = 100
-	sizeThresholdXL  = 500
-	sizeThresholdXXL = 1000
-)
+// Unbounded is an upper bound of the last tier, which matches any number of changed lines.
+const Unbounded = math.MaxInt
+
+// DefaultTiers is a list of tiers used when no configuration is provided.
+var DefaultTiers = []Tier{
+	{Name: "XS", Max: 9, Label: "size/XS"},
+	{Name: "S", Max: 29, Label: "size/S"},
+	{Name: "M", Max: 99, Label: "size/M"},
+	{Name: "L", Max: 499, Label: "size/L"},
+	{Name: "XL", Max: 999, Label: "size/XL"},
+	{Name: "XXL", Max: Unbounded, Label: "size/XXL"},
+}
 
-type Size int
+// Tier is a named range of the number of changed lines.
+type Tier struct {
+	// Name is a short name of the tier (e.g. "XL").
+	Name string
+	// Max is an inclusive upper bound of the number of changed lines.
+	Max int
+	// Label is a name of a label attached to pull requests in the tier.
+	Label string
+	// Color is a hexadecimal color code of the label without "#" (e.g. "ee0701").  It may be empty.
+	Color string
+	// Description is a description of the label.  It may be empty.
+	Description string
+}
 
-const (
-	SizeXS Size = iota
-	SizeS
-	SizeM
-	SizeL
-	SizeXL
-	SizeXXL
-)
+func (t Tier) String() string {
+	return t.Name
+}
+
+func (t Tier) GetLabel() string {
+	return t.Label
+}
+
+// Sizer classifies pull requests into an ordered list of tiers.
+type Sizer struct {
+	tiers []Tier
+}
+
+var defaultSizer = &Sizer{tiers: DefaultTiers}
 
-func (s Size) String() string {
-	switch s {
-	case SizeXS:
-		return "XS"
-	case SizeS:
-		return "S"
-	case SizeM:
-		return "M"
-	case SizeL:
-		return "L"
-	case SizeXL:
-		return "XL"
-	case SizeXXL:
-		return "XXL"
-	default:
-		return "Unknown"
+// DefaultSizer returns a sizer with the default tiers.
+func DefaultSizer() *Sizer {
+	return defaultSizer
+}
+
+// NewSizer validates the tiers and returns a sizer with them.  The tiers must be sorted by their upper bounds in
+// ascending order and the last tier must be unbounded.
+func NewSizer(tiers []Tier) (*Sizer, error) {
+	if len(tiers) == 0 {
+		return nil, errors.New("at least one tier is required")
+	}
+	names := make(map[string]struct{}, len(tiers))
+	labels := make(map[string]struct{}, len(tiers))
+	for i, tier := range tiers {
+		if tier.Name == "" {
+			return nil, fmt.Errorf("tier #%d has no name", i)
+		}
+		if _, ok := names[tier.Name]; ok {
+			return nil, fmt.Errorf("tier name %q is duplicated", tier.Name)
+		}
+		names[tier.Name] = struct{}{}
+		if tier.Label == "" {
+			return nil, fmt.Errorf("tier %q has no label", tier.Name)
+		}
+		if _, ok := labels[tier.Label]; ok {
+			return nil, fmt.Errorf("tier label %q is duplicated", tier.Label)
+		}
+		labels[tier.Label] = struct{}{}
+		if tier.Max < 0 {
+			return nil, fmt.Errorf("tier %q has a negative upper bound %d", tier.Name, tier.Max)
+		}
+		if i > 0 && tier.Max <= tiers[i-1].Max {
+			return nil, fmt.Errorf(
+				"tier %q must have a larger upper bound than tier %q: %d <= %d",
+				tier.Name, tiers[i-1].Name, tier.Max, tiers[i-1].Max,
+			)
+		}
 	}
+	if last := tiers[len(tiers)-1]; last.Max != Unbounded {
+		return nil, fmt.Errorf("the last tier %q must not have an upper bound", last.Name)
+	}
+	return &Sizer{tiers: append([]Tier(nil), tiers...)}, nil
+}
+
+// Tiers returns the tiers of the sizer.
+func (s *Sizer) Tiers() []Tier {
+	return append([]Tier(nil), s.tiers...)
 }
 
-func (s Size) GetLabel() string {
-	switch s {
-	case SizeXS:
-		return labelXS
-	case SizeS:
-		return labelS
-	case SizeM:
-		return labelM
-	case SizeL:
-		return labelL
-	case SizeXL:
-		return labelXL
-	case SizeXXL:
-		return labelXXL
-	default:
-		return labelUnknown
+// Index returns a position of the tier with the name, or -1 if no such tier exists.
+func (s *Sizer) Index(name string) int {
+	for i, tier := range s.tiers {
+		if tier.Name == name {
+			return i
+		}
 	}
+	return -1
+}
+
+// Labels returns labels of the tiers of the sizer.
+func (s *Sizer) Labels() []string {
+	labels := make([]string, 0, len(s.tiers))
+	for _, tier := range s.tiers {
+		labels = append(labels, tier.Label)
+	}
+	return labels
+}
+
+// Size returns a tier which the number of changed lines falls into.
+func (s *Sizer) Size(change int) Tier {
+	return s.tiers[s.index(change)]
 }
 
-func NewSize(change int) Size {
-	switch {
-	case change < sizeThresholdS:
-		return SizeXS
-	case change < sizeThresholdM:
-		return SizeS
-	case change < sizeThresholdL:
-		return SizeM
-	case change < sizeThresholdXL:
-		return SizeL
-	case change < sizeThresholdXXL:
-		return SizeXL
-	default:
-		return SizeXXL
+func (s *Sizer) index(change int) int {
+	for i, tier := range s.tiers {
+		if change <= tier.Max {
+			return i
+		}
 	}
+	return len(s.tiers) - 1
 }
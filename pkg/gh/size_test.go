@@ -6,9 +6,15 @@ import (
 
 	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestNewSize(t *testing.T) {
+// tier returns the default tier with the name.
+func tier(name string) gh.Tier {
+	return gh.DefaultTiers[gh.DefaultSizer().Index(name)]
+}
+
+func TestDefaultSizer(t *testing.T) {
 	tcs := []struct {
 		change     int
 		wantLabel  string
@@ -96,16 +102,67 @@ func TestNewSize(t *testing.T) {
 		},
 	}
 	for _, tt := range tcs {
-		t.Run(fmt.Sprintf("newSize(%d) => %s / %s", tt.change, tt.wantLabel, tt.wantString), func(t *testing.T) {
-			got := gh.NewSize(tt.change)
+		t.Run(fmt.Sprintf("Size(%d) => %s / %s", tt.change, tt.wantLabel, tt.wantString), func(t *testing.T) {
+			got := gh.DefaultSizer().Size(tt.change)
 			assert.Equal(t, tt.wantLabel, got.GetLabel())
 			assert.Equal(t, tt.wantString, got.String())
 		})
 	}
 }
 
-func TestSizeUnknown(t *testing.T) {
-	var size gh.Size = 999
-	assert.Equal(t, "Unknown", size.String())
-	assert.Equal(t, "size/?", size.GetLabel())
+func TestSizer(t *testing.T) {
+	sizer, err := gh.NewSizer([]gh.Tier{
+		{Name: "small", Max: 0, Label: "small"},
+		{Name: "medium", Max: 10, Label: "medium"},
+		{Name: "large", Max: gh.Unbounded, Label: "large"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "small", sizer.Size(0).String())
+	assert.Equal(t, "medium", sizer.Size(1).String())
+	assert.Equal(t, "medium", sizer.Size(10).GetLabel())
+	assert.Equal(t, "large", sizer.Size(11).GetLabel())
+	assert.Equal(t, gh.DefaultTiers, gh.DefaultSizer().Tiers())
+}
+
+func TestNewSizerReturnsError(t *testing.T) {
+	tcs := []struct {
+		name    string
+		tiers   []gh.Tier
+		wantErr string
+	}{
+		{
+			name:    "No tiers are specified.",
+			wantErr: "at least one tier is required",
+		},
+		{
+			name: "A tier has no label.",
+			tiers: []gh.Tier{
+				{Name: "large", Max: gh.Unbounded},
+			},
+			wantErr: `tier "large" has no label`,
+		},
+		{
+			name: "Labels are duplicated.",
+			tiers: []gh.Tier{
+				{Name: "small", Max: 1, Label: "size"},
+				{Name: "large", Max: gh.Unbounded, Label: "size"},
+			},
+			wantErr: `tier label "size" is duplicated`,
+		},
+		{
+			name: "An upper bound is negative.",
+			tiers: []gh.Tier{
+				{Name: "small", Max: -1, Label: "small"},
+				{Name: "large", Max: gh.Unbounded, Label: "large"},
+			},
+			wantErr: `tier "small" has a negative upper bound -1`,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gh.NewSizer(tt.tiers)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
 }
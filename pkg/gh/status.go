@@ -0,0 +1,58 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v29/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxStatusDescriptionLength is the maximum length of a description of a commit status.
+const maxStatusDescriptionLength = 140
+
+// CommitStatus is a commit status which reports the size of a pull request.
+type CommitStatus struct {
+	// Context is a name which distinguishes the status from ones of other systems.
+	Context string
+	// State is one of "success", "failure", "error" and "pending".
+	State string
+	// Description is a short description of the status, which is truncated if it's too long.
+	Description string
+	// TargetURL is a URL linked from the status.  It may be empty.
+	TargetURL string
+}
+
+// SetCommitStatus creates a commit status on the commit.  A newer status with the same context replaces older ones.
+func SetCommitStatus(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, sha string,
+	status *CommitStatus,
+) error {
+	logger := log.FromContext(ctx).WithValues(
+		"owner", owner,
+		"repo", repo,
+		"sha", sha,
+		"context", status.Context,
+	)
+
+	description := []rune(status.Description)
+	if len(description) > maxStatusDescriptionLength {
+		description = append(description[:maxStatusDescriptionLength-1], '…')
+	}
+	s := &github.RepoStatus{
+		Context:     github.String(status.Context),
+		State:       github.String(status.State),
+		Description: github.String(string(description)),
+	}
+	if status.TargetURL != "" {
+		s.TargetURL = github.String(status.TargetURL)
+	}
+	if _, _, err := client.Repositories.CreateStatus(ctx, owner, repo, sha, s); err != nil {
+		logger.Error(err, "Failed to create a commit status")
+		return fmt.Errorf("create a commit status: %w", err)
+	}
+	logger.Info("A commit status was created", "state", status.State)
+	return nil
+}
@@ -0,0 +1,107 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/jarcoal/httpmock"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCommitStatus(t *testing.T) {
+	tcs := []struct {
+		name   string
+		status *gh.CommitStatus
+		want   *github.RepoStatus
+	}{
+		{
+			name: "A status without a target URL.",
+			status: &gh.CommitStatus{
+				Context:     "pr-size",
+				State:       "success",
+				Description: "L — 342 lines in 12 files",
+			},
+			want: &github.RepoStatus{
+				Context:     github.String("pr-size"),
+				State:       github.String("success"),
+				Description: github.String("L — 342 lines in 12 files"),
+			},
+		},
+		{
+			name: "A status with a target URL and a long description.",
+			status: &gh.CommitStatus{
+				Context:     "pr-size",
+				State:       "failure",
+				Description: strings.Repeat("あ", 200),
+				TargetURL:   "https://github.com/kkohtaka/gh-actions-pr-size/actions/runs/1",
+			},
+			want: &github.RepoStatus{
+				Context:     github.String("pr-size"),
+				State:       github.String("failure"),
+				Description: github.String(strings.Repeat("あ", 139) + "…"),
+				TargetURL:   github.String("https://github.com/kkohtaka/gh-actions-pr-size/actions/runs/1"),
+			},
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			defer httpmock.DeactivateAndReset()
+
+			var got github.RepoStatus
+			httpmock.RegisterResponder(
+				"POST",
+				"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/statuses/abc",
+				func(req *http.Request) (*http.Response, error) {
+					if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+						return nil, err
+					}
+					return httpmock.NewJsonResponse(201, got)
+				},
+			)
+
+			err := gh.SetCommitStatus(
+				context.Background(),
+				github.NewClient(client),
+				"kkohtaka",
+				"gh-actions-pr-size",
+				"abc",
+				tt.status,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, &got)
+			assert.LessOrEqual(t, utf8.RuneCountInString(got.GetDescription()), 140)
+		})
+	}
+}
+
+func TestSetCommitStatusReturnsError(t *testing.T) {
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.github.com/repos/kkohtaka/gh-actions-pr-size/statuses/abc",
+		httpmock.NewErrorResponder(fmt.Errorf("test for error handling")),
+	)
+
+	err := gh.SetCommitStatus(
+		context.Background(),
+		github.NewClient(client),
+		"kkohtaka",
+		"gh-actions-pr-size",
+		"abc",
+		&gh.CommitStatus{Context: "pr-size", State: "success"},
+	)
+	assert.ErrorContains(t, err, "create a commit status: ")
+}
@@ -0,0 +1,199 @@
+// Package git computes changes between commits of a local repository by the git command, so that the size of changes
+// can be computed without GitHub API.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+)
+
+// Repository is a local git repository.
+type Repository struct {
+	dir string
+}
+
+// NewRepository returns a repository in the directory.  The directory may be any subdirectory of a work tree.
+func NewRepository(dir string) *Repository {
+	return &Repository{dir: dir}
+}
+
+// run runs the git command in the repository and returns its standard output.
+func (r *Repository) run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// MergeBase returns a SHA of the best common ancestor of the commits.
+func (r *Repository) MergeBase(ctx context.Context, base, head string) (string, error) {
+	out, err := r.run(ctx, "merge-base", base, head)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Diff returns files changed between the commits with their numbers of changed lines.  Renames are detected in the
+// same way as GitHub does, and binary files have no changed lines.
+func (r *Repository) Diff(ctx context.Context, from, to string) ([]*gh.File, error) {
+	out, err := r.run(ctx, "diff", "-z", "-M", "--no-ext-diff", "--name-status", from, to, "--")
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := parseNameStatus(out)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = r.run(ctx, "diff", "-z", "-M", "--no-ext-diff", "--numstat", from, to, "--")
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseNumstat(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		f.Status = statuses[f.Filename]
+	}
+	return files, nil
+}
+
+// statusNames maps status letters of "git diff --name-status" to statuses of files in GitHub API.
+var statusNames = map[byte]string{
+	'A': "added",
+	'C': "copied",
+	'D': "removed",
+	'M': "modified",
+	'R': "renamed",
+	'T': "changed",
+}
+
+// parseNameStatus parses an output of "git diff -z --name-status" into statuses by paths at the head.
+func parseNameStatus(out []byte) (map[string]string, error) {
+	res := make(map[string]string)
+	fields := splitNUL(out)
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		if status == "" {
+			return nil, fmt.Errorf("malformed output of git diff --name-status: empty status at %d", i)
+		}
+		// Renames and copies have both the source and the destination paths.
+		n := 1
+		if status[0] == 'R' || status[0] == 'C' {
+			n = 2
+		}
+		if i+n >= len(fields) {
+			return nil, fmt.Errorf("malformed output of git diff --name-status: no path for %q", status)
+		}
+		res[fields[i+n]] = statusNames[status[0]]
+		i += n + 1
+	}
+	return res, nil
+}
+
+// parseNumstat parses an output of "git diff -z --numstat" into files.
+func parseNumstat(out []byte) ([]*gh.File, error) {
+	var res []*gh.File
+	fields := splitNUL(out)
+	for i := 0; i < len(fields); {
+		stat := strings.SplitN(fields[i], "\t", 3)
+		if len(stat) != 3 {
+			return nil, fmt.Errorf("malformed output of git diff --numstat: %q", fields[i])
+		}
+		f := &gh.File{Filename: stat[2]}
+		i++
+		// A rename has an empty path followed by the source and the destination paths.
+		if f.Filename == "" {
+			if i+1 >= len(fields) {
+				return nil, errors.New("malformed output of git diff --numstat: no paths of a rename")
+			}
+			f.Filename = fields[i+1]
+			i += 2
+		}
+		var err error
+		if f.Additions, err = parseCount(stat[0]); err != nil {
+			return nil, err
+		}
+		if f.Deletions, err = parseCount(stat[1]); err != nil {
+			return nil, err
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// parseCount parses a number of changed lines, which is "-" for binary files.
+func parseCount(s string) (int, error) {
+	if s == "-" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("malformed output of git diff --numstat: %w", err)
+	}
+	return n, nil
+}
+
+// splitNUL splits NUL-terminated fields.
+func splitNUL(out []byte) []string {
+	s := strings.TrimSuffix(string(out), "\x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+// Tree is a tree of a local repository at a ref.
+type Tree struct {
+	repo *Repository
+	ref  string
+}
+
+var _ gh.Tree = &Tree{}
+
+// Tree returns a tree of the repository at the ref.
+func (r *Repository) Tree(ref string) *Tree {
+	return &Tree{repo: r, ref: ref}
+}
+
+// Find implements gh.Tree.  A local tree is always listed at once.
+func (t *Tree) Find(ctx context.Context, name string) ([]string, bool, error) {
+	out, err := t.repo.run(ctx, "ls-tree", "-r", "-z", "--name-only", "--full-tree", t.ref)
+	if err != nil {
+		return nil, false, err
+	}
+	var res []string
+	for _, p := range splitNUL(out) {
+		if path.Base(p) == name {
+			res = append(res, p)
+		}
+	}
+	return res, true, nil
+}
+
+// ReadFile implements gh.Tree.
+func (t *Tree) ReadFile(ctx context.Context, p string) ([]byte, error) {
+	out, err := t.repo.run(ctx, "ls-tree", "-z", "--full-tree", t.ref, "--", p)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, gh.ErrFileNotFound
+	}
+	return t.repo.run(ctx, "cat-file", "blob", t.ref+":"+p)
+}
@@ -0,0 +1,102 @@
+package git_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRepository creates a repository in which "feature" branches from "main" and "main" advances afterwards.
+func newRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	run("init", "-q", "-b", "main")
+	write("a.txt", "1\n2\n3\n")
+	write("old.txt", strings.Repeat("line\n", 20))
+	write("removed.txt", "x\ny\n")
+	write(".gitattributes", "gen/** linguist-generated\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "base")
+
+	run("checkout", "-q", "-b", "feature")
+	write("a.txt", "1\ntwo\n3\n4\n")
+	write("gen/api.go", "package gen\n")
+	write("bin.dat", "\x00\x01\x02")
+	require.NoError(t, os.Rename(filepath.Join(dir, "old.txt"), filepath.Join(dir, "new.txt")))
+	write("new.txt", strings.Repeat("line\n", 20)+"more\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "removed.txt")))
+	run("add", "-A")
+	run("commit", "-q", "-m", "feature")
+
+	run("checkout", "-q", "main")
+	write("main.txt", "not in the feature\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "advance")
+	return dir
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	repo := git.NewRepository(newRepository(t))
+
+	base, err := repo.MergeBase(ctx, "main", "feature")
+	require.NoError(t, err)
+	files, err := repo.Diff(ctx, base, "feature")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*gh.File{
+		{Filename: "a.txt", Status: "modified", Additions: 2, Deletions: 1},
+		{Filename: "bin.dat", Status: "added"},
+		{Filename: "gen/api.go", Status: "added", Additions: 1},
+		{Filename: "new.txt", Status: "renamed", Additions: 1},
+		{Filename: "removed.txt", Status: "removed", Deletions: 2},
+	}, files)
+
+	_, err = repo.MergeBase(ctx, "main", "unknown")
+	assert.ErrorContains(t, err, "git merge-base: ")
+}
+
+func TestTree(t *testing.T) {
+	ctx := context.Background()
+	repo := git.NewRepository(newRepository(t))
+	tree := repo.Tree("feature")
+
+	paths, complete, err := tree.Find(ctx, ".gitattributes")
+	require.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, []string{".gitattributes"}, paths)
+
+	data, err := tree.ReadFile(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "1\ntwo\n3\n4\n", string(data))
+
+	_, err = tree.ReadFile(ctx, "main.txt")
+	assert.ErrorIs(t, err, gh.ErrFileNotFound)
+
+	filter := gh.NewTreeLinguistFilter(tree)
+	reason, err := filter.Exclude(ctx, "gen/api.go")
+	require.NoError(t, err)
+	assert.Equal(t, "linguist-generated", reason)
+}
@@ -0,0 +1,169 @@
+// Package gitattributes implements a subset of gitattributes(5), which is enough to look up attributes assigned to
+// paths by .gitattributes files in a repository.
+package gitattributes
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/glob"
+)
+
+// FileName is a name of files which assign attributes to paths.
+const FileName = ".gitattributes"
+
+// State is a state of an attribute for a path.
+type State int
+
+const (
+	// Unspecified means no pattern matches the path with the attribute.
+	Unspecified State = iota
+	// Set means the attribute is set (e.g. "text").
+	Set
+	// Unset means the attribute is unset (e.g. "-text").
+	Unset
+	// Value means the attribute is set to a value (e.g. "text=auto").
+	Value
+)
+
+// File is a parsed .gitattributes file.
+type File struct {
+	dir   string
+	rules []rule
+}
+
+type rule struct {
+	pattern string
+	attrs   []attr
+}
+
+type attr struct {
+	name  string
+	state State
+	value string
+}
+
+// Parse parses the content of a .gitattributes file in the directory, which is a slash-separated path relative to the
+// root of a repository ("" for the root).  Malformed lines are ignored as git does.
+func Parse(dir string, data []byte) *File {
+	f := &File{dir: strings.Trim(dir, "/")}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, rest := splitPattern(line)
+		// Negative patterns are forbidden in .gitattributes files, and patterns for directories never match files.
+		if pattern == "" || strings.HasPrefix(pattern, "!") || strings.HasSuffix(pattern, "/") {
+			continue
+		}
+		r := rule{pattern: pattern}
+		for _, field := range strings.Fields(rest) {
+			switch {
+			case strings.HasPrefix(field, "-"):
+				r.attrs = append(r.attrs, attr{name: field[1:], state: Unset})
+			case strings.HasPrefix(field, "!"):
+				r.attrs = append(r.attrs, attr{name: field[1:], state: Unspecified})
+			case strings.Contains(field, "="):
+				kv := strings.SplitN(field, "=", 2)
+				r.attrs = append(r.attrs, attr{name: kv[0], state: Value, value: kv[1]})
+			default:
+				r.attrs = append(r.attrs, attr{name: field, state: Set})
+			}
+		}
+		f.rules = append(f.rules, r)
+	}
+	return f
+}
+
+// splitPattern splits a line into a pattern, which may be quoted, and the rest of the line.
+func splitPattern(line string) (string, string) {
+	if strings.HasPrefix(line, `"`) {
+		for i := 1; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '"' {
+				pattern, err := strconv.Unquote(line[:i+1])
+				if err != nil {
+					return "", ""
+				}
+				return pattern, line[i+1:]
+			}
+		}
+		return "", ""
+	}
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i], line[i:]
+	}
+	return line, ""
+}
+
+// match reports whether the rule matches a slash-separated path relative to the directory of the file.
+func (r *rule) match(rel string) bool {
+	if !strings.Contains(r.pattern, "/") {
+		return glob.Match(r.pattern, path.Base(rel))
+	}
+	return glob.Match(strings.TrimPrefix(r.pattern, "/"), rel)
+}
+
+// Attributes looks up attributes assigned by a set of .gitattributes files.
+type Attributes struct {
+	files []*File
+}
+
+// New returns attributes assigned by the files.  Files in deeper directories take precedence over files in their
+// ancestors.
+func New(files ...*File) *Attributes {
+	sorted := append([]*File(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return depth(sorted[i].dir) < depth(sorted[j].dir)
+	})
+	return &Attributes{files: sorted}
+}
+
+func depth(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// Lookup returns the state and the value of an attribute for a slash-separated path relative to the root of a
+// repository.
+func (a *Attributes) Lookup(name, attrName string) (State, string) {
+	state, value := Unspecified, ""
+	for _, f := range a.files {
+		rel := name
+		if f.dir != "" {
+			if !strings.HasPrefix(name, f.dir+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, f.dir+"/")
+		}
+		for _, r := range f.rules {
+			if !r.match(rel) {
+				continue
+			}
+			for _, at := range r.attrs {
+				if at.name == attrName {
+					state, value = at.state, at.value
+				}
+			}
+		}
+	}
+	return state, value
+}
+
+// IsTrue reports whether an attribute is set or set to "true" for a path, as GitHub Linguist interprets its
+// attributes.
+func (a *Attributes) IsTrue(name, attrName string) bool {
+	state, value := a.Lookup(name, attrName)
+	return state == Set || (state == Value && value == "true")
+}
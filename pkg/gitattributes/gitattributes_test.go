@@ -0,0 +1,57 @@
+package gitattributes_test
+
+import (
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gitattributes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributes(t *testing.T) {
+	attrs := gitattributes.New(
+		gitattributes.Parse("api", []byte(`
+# Generated code under api/ is ignored, except for the hand-written one.
+*.pb.go linguist-generated
+handwritten.pb.go -linguist-generated
+/v1/docs/** linguist-documentation
+`)),
+		gitattributes.Parse("", []byte(`
+*.pb.go linguist-generated=false
+mocks/** linguist-generated=true
+vendor/** linguist-vendored
+third_party/** linguist-vendored
+third_party/ours/** !linguist-vendored
+"with space.txt" linguist-generated
+build/ linguist-generated
+!*.md linguist-generated
+`)),
+	)
+
+	tcs := []struct {
+		name      string
+		attr      string
+		wantState gitattributes.State
+		wantTrue  bool
+	}{
+		{name: "api/v1/foo.pb.go", attr: "linguist-generated", wantState: gitattributes.Set, wantTrue: true},
+		{name: "api/handwritten.pb.go", attr: "linguist-generated", wantState: gitattributes.Unset},
+		{name: "pkg/foo.pb.go", attr: "linguist-generated", wantState: gitattributes.Value},
+		{name: "mocks/mock.go", attr: "linguist-generated", wantState: gitattributes.Value, wantTrue: true},
+		{name: "pkg/mocks/mock.go", attr: "linguist-generated", wantState: gitattributes.Unspecified},
+		{name: "vendor/a/b.go", attr: "linguist-vendored", wantState: gitattributes.Set, wantTrue: true},
+		{name: "third_party/x/y.go", attr: "linguist-vendored", wantState: gitattributes.Set, wantTrue: true},
+		{name: "third_party/ours/y.go", attr: "linguist-vendored", wantState: gitattributes.Unspecified},
+		{name: "api/v1/docs/a.md", attr: "linguist-documentation", wantState: gitattributes.Set, wantTrue: true},
+		{name: "v1/docs/a.md", attr: "linguist-documentation", wantState: gitattributes.Unspecified},
+		{name: "with space.txt", attr: "linguist-generated", wantState: gitattributes.Set, wantTrue: true},
+		{name: "build/out.js", attr: "linguist-generated", wantState: gitattributes.Unspecified},
+		{name: "README.md", attr: "linguist-generated", wantState: gitattributes.Unspecified},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			state, _ := attrs.Lookup(tt.name, tt.attr)
+			assert.Equal(t, tt.wantState, state)
+			assert.Equal(t, tt.wantTrue, attrs.IsTrue(tt.name, tt.attr))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Package glob implements matching of slash-separated paths against glob patterns, where "**" matches zero or more
+// directories.
+package glob
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const doubleStar = "**"
+
+// Match reports whether name matches the pattern.  Each segment of the pattern is matched with path.Match against a
+// segment of name, except a segment consisting of "**", which matches zero or more segments.  A malformed pattern
+// never matches.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Validate returns an error if the pattern is malformed.
+func Validate(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == doubleStar {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("malformed pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == doubleStar {
+			// Collapse consecutive "**" since they are equivalent to a single one.
+			for len(patterns) > 1 && patterns[1] == doubleStar {
+				patterns = patterns[1:]
+			}
+			if len(patterns) == 1 {
+				return true
+			}
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, err := path.Match(patterns[0], names[0]); err != nil || !ok {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
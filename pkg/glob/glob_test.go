@@ -0,0 +1,45 @@
+package glob_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/glob"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tcs := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "go.sum", name: "go.sum", want: true},
+		{pattern: "go.sum", name: "sub/go.sum", want: false},
+		{pattern: "*.go", name: "main.go", want: true},
+		{pattern: "*.go", name: "cmd/main.go", want: false},
+		{pattern: "**/*.go", name: "main.go", want: true},
+		{pattern: "**/*.go", name: "cmd/gh/main.go", want: true},
+		{pattern: "**/go.sum", name: "tools/go.sum", want: true},
+		{pattern: "vendor/**", name: "vendor/a/b.go", want: true},
+		{pattern: "vendor/**", name: "pkg/vendor/b.go", want: false},
+		{pattern: "a/**/b", name: "a/b", want: true},
+		{pattern: "a/**/b", name: "a/x/y/b", want: true},
+		{pattern: "a/**/**/b", name: "a/x/b", want: true},
+		{pattern: "a/**/b", name: "a/x/c", want: false},
+		{pattern: "**/__snapshots__/**", name: "web/__snapshots__/app.snap", want: true},
+		{pattern: "file?.[ch]", name: "file1.c", want: true},
+		{pattern: "[", name: "[", want: false},
+	}
+	for _, tt := range tcs {
+		t.Run(fmt.Sprintf("Match(%q, %q) => %v", tt.pattern, tt.name, tt.want), func(t *testing.T) {
+			assert.Equal(t, tt.want, glob.Match(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, glob.Validate("**/*.pb.go"))
+	assert.ErrorContains(t, glob.Validate("[a"), "malformed pattern")
+	assert.ErrorContains(t, glob.Validate(""), "empty pattern")
+}
@@ -0,0 +1,111 @@
+// Package policy decides whether the size of a pull request is acceptable.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+)
+
+// Policy is a rule which limits the size of pull requests.
+type Policy struct {
+	// MaxSize is a name of the largest acceptable tier.  If it's empty, pull requests of any size are accepted.
+	MaxSize string `yaml:"maxSize,omitempty"`
+	// BypassLabel is a label which exempts a pull request from MaxSize.
+	BypassLabel string `yaml:"bypassLabel,omitempty"`
+	// BypassKeyword is a keyword in a body of a pull request which exempts the pull request from MaxSize.
+	BypassKeyword string `yaml:"bypassKeyword,omitempty"`
+}
+
+// Result is a result of evaluating a policy.
+type Result int
+
+const (
+	// Pass means the pull request satisfies the policy.
+	Pass Result = iota
+	// Warn means the pull request violates the policy but is exempted from it.
+	Warn
+	// Fail means the pull request violates the policy.
+	Fail
+)
+
+func (r Result) String() string {
+	switch r {
+	case Pass:
+		return "Pass"
+	case Warn:
+		return "Warn"
+	case Fail:
+		return "Fail"
+	default:
+		return "Unknown"
+	}
+}
+
+// Conclusion returns a conclusion of a check run which reports the result.
+func (r Result) Conclusion() string {
+	switch r {
+	case Pass:
+		return "success"
+	case Warn:
+		return "neutral"
+	default:
+		return "failure"
+	}
+}
+
+// State returns a state of a commit status which reports the result.  Commit statuses have no state corresponding to
+// Warn, so exempted pull requests succeed.
+func (r Result) State() string {
+	if r == Fail {
+		return "failure"
+	}
+	return "success"
+}
+
+// Verdict is a result of evaluating a policy with its explanation.
+type Verdict struct {
+	Result Result
+	// Message explains the result.
+	Message string
+}
+
+// Validate returns an error if the policy refers to a tier which the sizer doesn't have.
+func (p *Policy) Validate(sizer *gh.Sizer) error {
+	if p.MaxSize != "" && sizer.Index(p.MaxSize) < 0 {
+		return fmt.Errorf("max size %q is not a tier", p.MaxSize)
+	}
+	return nil
+}
+
+// Evaluate decides whether a pull request in the tier satisfies the policy.
+func (p *Policy) Evaluate(sizer *gh.Sizer, size gh.Tier, pr *github.PullRequest) Verdict {
+	max := sizer.Index(p.MaxSize)
+	if max < 0 || sizer.Index(size.Name) <= max {
+		return Verdict{
+			Result:  Pass,
+			Message: fmt.Sprintf("The pull request size %s is acceptable.", size.Name),
+		}
+	}
+
+	exceeded := fmt.Sprintf("The pull request size %s exceeds the maximum size %s", size.Name, p.MaxSize)
+	if p.BypassLabel != "" {
+		for _, label := range pr.Labels {
+			if label.GetName() == p.BypassLabel {
+				return Verdict{
+					Result:  Warn,
+					Message: fmt.Sprintf("%s, but it's exempted by the label %q.", exceeded, p.BypassLabel),
+				}
+			}
+		}
+	}
+	if p.BypassKeyword != "" && strings.Contains(pr.GetBody(), p.BypassKeyword) {
+		return Verdict{
+			Result:  Warn,
+			Message: fmt.Sprintf("%s, but it's exempted by the keyword %q.", exceeded, p.BypassKeyword),
+		}
+	}
+	return Verdict{Result: Fail, Message: exceeded + "."}
+}
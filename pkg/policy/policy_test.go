@@ -0,0 +1,90 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	sizer := gh.DefaultSizer()
+	tcs := []struct {
+		name        string
+		policy      policy.Policy
+		size        string
+		pr          *github.PullRequest
+		wantResult  policy.Result
+		wantMessage string
+	}{
+		{
+			name:        "No maximum size is specified.",
+			size:        "XXL",
+			pr:          &github.PullRequest{},
+			wantResult:  policy.Pass,
+			wantMessage: "The pull request size XXL is acceptable.",
+		},
+		{
+			name:        "The pull request is as large as the maximum size.",
+			policy:      policy.Policy{MaxSize: "XL"},
+			size:        "XL",
+			pr:          &github.PullRequest{},
+			wantResult:  policy.Pass,
+			wantMessage: "The pull request size XL is acceptable.",
+		},
+		{
+			name:        "The pull request exceeds the maximum size.",
+			policy:      policy.Policy{MaxSize: "XL", BypassLabel: "skip-size", BypassKeyword: "[skip size]"},
+			size:        "XXL",
+			pr:          &github.PullRequest{Body: github.String("Big change")},
+			wantResult:  policy.Fail,
+			wantMessage: "The pull request size XXL exceeds the maximum size XL.",
+		},
+		{
+			name:   "The pull request has the bypass label.",
+			policy: policy.Policy{MaxSize: "XL", BypassLabel: "skip-size"},
+			size:   "XXL",
+			pr: &github.PullRequest{
+				Labels: []*github.Label{{Name: github.String("skip-size")}},
+			},
+			wantResult:  policy.Warn,
+			wantMessage: `The pull request size XXL exceeds the maximum size XL, but it's exempted by the label "skip-size".`,
+		},
+		{
+			name:        "The pull request has the bypass keyword in its body.",
+			policy:      policy.Policy{MaxSize: "L", BypassKeyword: "[skip size]"},
+			size:        "XL",
+			pr:          &github.PullRequest{Body: github.String("Generated code.\n[skip size]")},
+			wantResult:  policy.Warn,
+			wantMessage: `The pull request size XL exceeds the maximum size L, but it's exempted by the keyword "[skip size]".`,
+		},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Evaluate(sizer, gh.DefaultTiers[sizer.Index(tt.size)], tt.pr)
+			assert.Equal(t, tt.wantResult, got.Result)
+			assert.Equal(t, tt.wantMessage, got.Message)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	sizer := gh.DefaultSizer()
+	assert.NoError(t, (&policy.Policy{}).Validate(sizer))
+	assert.NoError(t, (&policy.Policy{MaxSize: "XL"}).Validate(sizer))
+	assert.ErrorContains(t, (&policy.Policy{MaxSize: "XXXL"}).Validate(sizer), `max size "XXXL" is not a tier`)
+}
+
+func TestResultConclusion(t *testing.T) {
+	assert.Equal(t, "success", policy.Pass.Conclusion())
+	assert.Equal(t, "neutral", policy.Warn.Conclusion())
+	assert.Equal(t, "failure", policy.Fail.Conclusion())
+}
+
+func TestResultState(t *testing.T) {
+	assert.Equal(t, "success", policy.Pass.State())
+	assert.Equal(t, "success", policy.Warn.State())
+	assert.Equal(t, "failure", policy.Fail.State())
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relabel is a result of relabeling a pull request in a backfill.
+type Relabel struct {
+	// Number is a number of the pull request.
+	Number int
+	// Title is a title of the pull request.
+	Title string
+	// Before is a list of labels of tiers which the pull request had.
+	Before []string
+	// After is a label of the tier which the pull request falls into.  It's empty if the size couldn't be computed.
+	After string
+	// Err is an error occurred on relabeling the pull request.
+	Err error
+}
+
+// Changed reports whether the labels of tiers on the pull request are changed by the relabeling.
+func (r *Relabel) Changed() bool {
+	return r.Err == nil && (len(r.Before) != 1 || r.Before[0] != r.After)
+}
+
+// Backfill renders results of relabeling pull requests as a Markdown document suitable for a job summary.  Pull
+// requests whose labels are unchanged are only counted.
+func Backfill(relabels []Relabel, dryRun bool) string {
+	var changed, failed int
+	for i := range relabels {
+		switch {
+		case relabels[i].Err != nil:
+			failed++
+		case relabels[i].Changed():
+			changed++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("## Pull request size backfill\n\n")
+	verb := "Relabeled"
+	if dryRun {
+		verb = "Would relabel"
+	}
+	fmt.Fprintf(&b, "%s **%d** of %d open pull requests.", verb, changed, len(relabels))
+	if failed > 0 {
+		fmt.Fprintf(&b, " %d pull requests failed.", failed)
+	}
+	b.WriteString("\n")
+	if changed+failed == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\n| Pull request | Before | After |\n")
+	b.WriteString("|--------------|--------|-------|\n")
+	for _, r := range relabels {
+		if r.Err == nil && !r.Changed() {
+			continue
+		}
+		before := make([]string, 0, len(r.Before))
+		for _, label := range r.Before {
+			before = append(before, code(label))
+		}
+		after := code(r.After)
+		if r.Err != nil {
+			after = "failed: " + escape(r.Err.Error())
+		}
+		fmt.Fprintf(&b, "| #%d %s | %s | %s |\n", r.Number, escape(r.Title), strings.Join(before, ", "), after)
+	}
+	return b.String()
+}
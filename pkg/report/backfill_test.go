@@ -0,0 +1,36 @@
+package report_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfill(t *testing.T) {
+	relabels := []report.Relabel{
+		{Number: 1, Title: "Unchanged", Before: []string{"size/S"}, After: "size/S"},
+		{Number: 2, Title: "Grown | bigger", Before: []string{"size/S"}, After: "size/L"},
+		{Number: 3, Title: "Unlabeled", After: "size/XS"},
+		{Number: 4, Title: "Broken", Before: []string{"size/S", "size/L"}, Err: errors.New("not found")},
+	}
+
+	assert.Equal(t, "## Pull request size backfill\n"+
+		"\n"+
+		"Relabeled **2** of 4 open pull requests. 1 pull requests failed.\n"+
+		"\n"+
+		"| Pull request | Before | After |\n"+
+		"|--------------|--------|-------|\n"+
+		"| #2 Grown \\| bigger | `size/S` | `size/L` |\n"+
+		"| #3 Unlabeled |  | `size/XS` |\n"+
+		"| #4 Broken | `size/S`, `size/L` | failed: not found |\n",
+		report.Backfill(relabels, false),
+	)
+
+	assert.Equal(t, "## Pull request size backfill\n"+
+		"\n"+
+		"Would relabel **0** of 1 open pull requests.\n",
+		report.Backfill(relabels[:1], true),
+	)
+}
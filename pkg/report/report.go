@@ -0,0 +1,267 @@
+// Package report renders results of computing the size of a pull request as Markdown.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v29/github"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+)
+
+const (
+	// maxLargestFiles is the maximum number of files listed as the largest ones.
+	maxLargestFiles = 10
+	// maxExcludedFiles is the maximum number of excluded files listed or annotated, so that reports stay within the
+	// limit of a summary of a check run even if thousands of files are excluded.  The largest ones are picked.
+	maxExcludedFiles = 40
+	// directoryDepth is the number of leading path segments by which files are grouped in a breakdown.
+	directoryDepth = 2
+)
+
+// Report is a result of computing the size of a pull request.
+type Report struct {
+	// Size is a tier which the pull request falls into.
+	Size gh.Tier
+	// Tiers is a list of tiers in effect.
+	Tiers []gh.Tier
+	// Changes is a breakdown of changed lines in the pull request.
+	Changes *gh.ChangedLines
+	// Override is an override of the size by a command, or nil if the size is computed from the changes.
+	Override *gh.Override
+}
+
+// Markdown renders the report as a Markdown document suitable for a job summary.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## Pull request size: %s\n\n", r.Size.Name)
+	fmt.Fprintf(&b,
+		"This pull request changes **%d** lines (+%d / -%d) in %d files and is labeled `%s`.\n",
+		r.Changes.Total(), r.Changes.Additions, r.Changes.Deletions, r.Changes.FileCount(), r.Size.Label,
+	)
+	if len(r.Changes.Excluded) > 0 {
+		fmt.Fprintf(&b, "%d lines in %d excluded files are not counted.\n",
+			r.Changes.ExcludedLines(), len(r.Changes.Excluded),
+		)
+	}
+	if r.Override != nil {
+		fmt.Fprintf(&b, "The size is pinned to %s by @%s with `/size override`.\n",
+			escape(r.Override.Tier.Name), escape(r.Override.User),
+		)
+	}
+	if r.Changes.Truncated {
+		fmt.Fprintf(&b, "\n> [!WARNING]\n> %s\n", r.IncompleteWarning())
+	}
+
+	if len(r.Changes.Files) == 0 && r.Changes.Unlisted > 0 && !r.Changes.Truncated {
+		b.WriteString("\nThe largest files are not listed since no files can be excluded from the size.\n")
+	}
+	if files := LargestFiles(r.Changes.Files, maxLargestFiles); len(files) > 0 {
+		b.WriteString("\n### Largest files\n\n")
+		b.WriteString("| File | Changed lines | Additions | Deletions |\n")
+		b.WriteString("|------|--------------:|----------:|----------:|\n")
+		for _, f := range files {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d |\n",
+				code(f.Filename), f.Changes(), f.Additions, f.Deletions,
+			)
+		}
+	}
+
+	if len(r.Changes.Excluded) > 0 {
+		b.WriteString("\n### Excluded files\n\n")
+		b.WriteString("| File | Changed lines | Reason |\n")
+		b.WriteString("|------|--------------:|--------|\n")
+		excluded := LargestExcludedFiles(r.Changes.Excluded, maxExcludedFiles)
+		lines := 0
+		for _, e := range excluded {
+			fmt.Fprintf(&b, "| %s | %d | %s |\n",
+				code(e.File.Filename), e.File.Changes(), escape(e.Reason),
+			)
+			lines += e.File.Changes()
+		}
+		if n := len(r.Changes.Excluded) - len(excluded); n > 0 {
+			fmt.Fprintf(&b, "\n%d more excluded files with %d changed lines are not listed.\n",
+				n, r.Changes.ExcludedLines()-lines,
+			)
+		}
+	}
+
+	b.WriteString("\n### Tiers\n\n")
+	b.WriteString("| Tier | Label | Changed lines |\n")
+	b.WriteString("|------|-------|---------------|\n")
+	for i, t := range r.Tiers {
+		name := escape(t.Name)
+		if t.Name == r.Size.Name {
+			name = "**" + name + "**"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", name, code(t.Label), Range(r.Tiers, i))
+	}
+	return b.String()
+}
+
+// LargestFiles returns at most n files sorted by the number of changed lines in descending order.
+func LargestFiles(files []*gh.File, n int) []*gh.File {
+	sorted := append([]*gh.File(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Changes() > sorted[j].Changes()
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// LargestExcludedFiles returns at most n excluded files sorted by the number of changed lines in descending order.
+func LargestExcludedFiles(excluded []gh.ExcludedFile, n int) []gh.ExcludedFile {
+	sorted := append([]gh.ExcludedFile(nil), excluded...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].File.Changes() > sorted[j].File.Changes()
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// Range returns a human-readable range of changed lines of the i-th tier (e.g. "100 - 499").
+func Range(tiers []gh.Tier, i int) string {
+	min := 0
+	if i > 0 {
+		min = tiers[i-1].Max + 1
+	}
+	if tiers[i].Max == gh.Unbounded {
+		return fmt.Sprintf("%d -", min)
+	}
+	return fmt.Sprintf("%d - %d", min, tiers[i].Max)
+}
+
+// escape escapes characters which break a cell of a Markdown table.
+func escape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// code renders a string as an inline code in a cell of a Markdown table.
+func code(s string) string {
+	return "`" + escape(strings.ReplaceAll(s, "`", "'")) + "`"
+}
+
+// Comment renders the report as a Markdown document suitable for a comment on the pull request.  If threshold is not
+// nil and the pull request is in the tier or larger, the comment suggests splitting the pull request.
+func (r *Report) Comment(threshold *gh.Tier) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "### Pull request size: %s\n\n", r.Size.Name)
+	fmt.Fprintf(&b,
+		"This pull request changes **%d** lines (+%d / -%d) in %d files.\n",
+		r.Changes.Total(), r.Changes.Additions, r.Changes.Deletions, r.Changes.FileCount(),
+	)
+	if len(r.Changes.Excluded) > 0 {
+		fmt.Fprintf(&b, "%d lines in %d excluded files are not counted.\n",
+			r.Changes.ExcludedLines(), len(r.Changes.Excluded),
+		)
+	}
+	if r.Changes.Truncated {
+		fmt.Fprintf(&b, "\n> [!WARNING]\n> %s\n", r.IncompleteWarning())
+	}
+	if threshold != nil && r.Size.Max >= threshold.Max {
+		fmt.Fprintf(&b,
+			"\n> [!TIP]\n> This pull request is `%s` or larger. Consider splitting it into smaller pull requests to make "+
+				"it easier to review.\n",
+			threshold.Name,
+		)
+	}
+
+	if dirs := Directories(r.Changes.Files, directoryDepth); len(dirs) > 0 {
+		b.WriteString("\n| Directory | Files | Changed lines |\n")
+		b.WriteString("|-----------|------:|--------------:|\n")
+		for _, d := range dirs {
+			fmt.Fprintf(&b, "| %s | %d | %d |\n", code(d.Path), d.Files, d.Changes)
+		}
+	}
+	return b.String()
+}
+
+// Directory is the number of changed lines in a directory.
+type Directory struct {
+	// Path is a path of the directory, which is "/" for files at the root of the repository.
+	Path string
+	// Files is the number of changed files in the directory.
+	Files int
+	// Changes is the number of changed lines in the directory.
+	Changes int
+}
+
+// Directories groups files by their directories truncated to depth segments and returns the directories sorted by the
+// number of changed lines in descending order.
+func Directories(files []*gh.File, depth int) []Directory {
+	index := make(map[string]int)
+	var dirs []Directory
+	for _, f := range files {
+		dir := "/"
+		if segments := strings.Split(f.Filename, "/"); len(segments) > 1 {
+			segments = segments[:len(segments)-1]
+			if len(segments) > depth {
+				segments = segments[:depth]
+			}
+			dir = strings.Join(segments, "/") + "/"
+		}
+		i, ok := index[dir]
+		if !ok {
+			i = len(dirs)
+			index[dir] = i
+			dirs = append(dirs, Directory{Path: dir})
+		}
+		dirs[i].Files++
+		dirs[i].Changes += f.Changes()
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return dirs[i].Changes > dirs[j].Changes
+	})
+	return dirs
+}
+
+// IncompleteWarning returns a message which tells that the breakdown by files is incomplete since GitHub API didn't
+// list all files.
+func (r *Report) IncompleteWarning() string {
+	return fmt.Sprintf(
+		"GitHub API lists at most %d files, so %d unlisted files are counted without filters and not broken down.",
+		gh.MaxListedFiles, r.Changes.Unlisted,
+	)
+}
+
+// Title returns a short description of the report (e.g. "L — 342 lines in 12 files").
+func (r *Report) Title() string {
+	return fmt.Sprintf("%s — %d lines in %d files", r.Size.Name, r.Changes.Total(), r.Changes.FileCount())
+}
+
+// Annotations returns annotations of check runs on the largest files and the largest excluded files, which fit in a
+// single request of Checks API.  Removed files are not annotated since they don't exist on the head commit.
+func (r *Report) Annotations() []*github.CheckRunAnnotation {
+	var res []*github.CheckRunAnnotation
+	annotate := func(f *gh.File, title, message string) {
+		if f.Status == "removed" {
+			return
+		}
+		res = append(res, &github.CheckRunAnnotation{
+			Path:            github.String(f.Filename),
+			StartLine:       github.Int(1),
+			EndLine:         github.Int(1),
+			AnnotationLevel: github.String("notice"),
+			Title:           github.String(title),
+			Message:         github.String(message),
+		})
+	}
+	for _, f := range LargestFiles(r.Changes.Files, maxLargestFiles) {
+		annotate(f, "Large change",
+			fmt.Sprintf("This file changes %d lines (+%d / -%d).", f.Changes(), f.Additions, f.Deletions),
+		)
+	}
+	for _, e := range LargestExcludedFiles(r.Changes.Excluded, maxExcludedFiles) {
+		annotate(e.File, "Excluded from the size",
+			fmt.Sprintf("%d changed lines in this file are not counted: %s.", e.File.Changes(), e.Reason),
+		)
+	}
+	return res
+}
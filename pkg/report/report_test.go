@@ -0,0 +1,172 @@
+package report_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/gh"
+	"github.com/kkohtaka/gh-actions-pr-size/pkg/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdown(t *testing.T) {
+	tiers := []gh.Tier{
+		{Name: "S", Max: 99, Label: "size/S"},
+		{Name: "L", Max: gh.Unbounded, Label: "size/L"},
+	}
+	r := &report.Report{
+		Size:  tiers[1],
+		Tiers: tiers,
+		Changes: &gh.ChangedLines{
+			Additions: 110,
+			Deletions: 20,
+			Files: []*gh.File{
+				{Filename: "small.go", Additions: 10},
+				{Filename: "large.go", Additions: 100, Deletions: 20},
+			},
+			Excluded: []gh.ExcludedFile{
+				{
+					File:   &gh.File{Filename: "go.sum", Additions: 300},
+					Reason: `excluded by "**/go.sum"`,
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, "## Pull request size: L\n"+
+		"\n"+
+		"This pull request changes **130** lines (+110 / -20) in 2 files and is labeled `size/L`.\n"+
+		"300 lines in 1 excluded files are not counted.\n"+
+		"\n"+
+		"### Largest files\n"+
+		"\n"+
+		"| File | Changed lines | Additions | Deletions |\n"+
+		"|------|--------------:|----------:|----------:|\n"+
+		"| `large.go` | 120 | 100 | 20 |\n"+
+		"| `small.go` | 10 | 10 | 0 |\n"+
+		"\n"+
+		"### Excluded files\n"+
+		"\n"+
+		"| File | Changed lines | Reason |\n"+
+		"|------|--------------:|--------|\n"+
+		"| `go.sum` | 300 | excluded by \"**/go.sum\" |\n"+
+		"\n"+
+		"### Tiers\n"+
+		"\n"+
+		"| Tier | Label | Changed lines |\n"+
+		"|------|-------|---------------|\n"+
+		"| S | `size/S` | 0 - 99 |\n"+
+		"| **L** | `size/L` | 100 - |\n",
+		r.Markdown(),
+	)
+
+	r.Override = &gh.Override{Tier: tiers[1], User: "maintainer"}
+	assert.Contains(t, r.Markdown(), "300 lines in 1 excluded files are not counted.\n"+
+		"The size is pinned to L by @maintainer with `/size override`.\n")
+
+	r.Changes.Unlisted = 3
+	r.Changes.Truncated = true
+	assert.Contains(t, r.Markdown(), "in 5 files and is labeled `size/L`.\n")
+	assert.Contains(t, r.Markdown(), "`/size override`.\n"+
+		"\n"+
+		"> [!WARNING]\n"+
+		"> GitHub API lists at most 3000 files, so 3 unlisted files are counted without filters and not broken down.\n")
+
+	r.Override = nil
+	r.Changes = &gh.ChangedLines{Additions: 110, Deletions: 20, Unlisted: 2}
+	assert.Contains(t, r.Markdown(), "in 2 files and is labeled `size/L`.\n"+
+		"\n"+
+		"The largest files are not listed since no files can be excluded from the size.\n"+
+		"\n"+
+		"### Tiers\n")
+}
+
+func TestLargestFiles(t *testing.T) {
+	files := []*gh.File{
+		{Filename: "a", Additions: 1},
+		{Filename: "b", Additions: 3},
+		{Filename: "c", Deletions: 2},
+	}
+	got := report.LargestFiles(files, 2)
+	assert.Equal(t, []*gh.File{files[1], files[2]}, got)
+	assert.Equal(t, "a", files[0].Filename, "the argument must not be modified")
+}
+
+func TestComment(t *testing.T) {
+	tiers := []gh.Tier{
+		{Name: "S", Max: 99, Label: "size/S"},
+		{Name: "L", Max: gh.Unbounded, Label: "size/L"},
+	}
+	r := &report.Report{
+		Size:  tiers[1],
+		Tiers: tiers,
+		Changes: &gh.ChangedLines{
+			Additions: 130,
+			Files: []*gh.File{
+				{Filename: "README.md", Additions: 10},
+				{Filename: "pkg/gh/gh.go", Additions: 100},
+				{Filename: "pkg/gh/sub/size.go", Additions: 20},
+			},
+		},
+	}
+
+	want := "### Pull request size: L\n" +
+		"\n" +
+		"This pull request changes **130** lines (+130 / -0) in 3 files.\n" +
+		"\n" +
+		"| Directory | Files | Changed lines |\n" +
+		"|-----------|------:|--------------:|\n" +
+		"| `pkg/gh/` | 2 | 120 |\n" +
+		"| `/` | 1 | 10 |\n"
+	assert.Equal(t, want, r.Comment(nil))
+
+	assert.Contains(t, r.Comment(&tiers[1]), "This pull request is `L` or larger.")
+	r.Size = tiers[0]
+	assert.NotContains(t, r.Comment(&tiers[1]), "This pull request is `L` or larger.")
+}
+
+func TestAnnotations(t *testing.T) {
+	r := &report.Report{
+		Size: gh.DefaultSizer().Size(50),
+		Changes: &gh.ChangedLines{
+			Additions: 40,
+			Files: []*gh.File{
+				{Filename: "main.go", Additions: 40},
+				{Filename: "old.go", Deletions: 10, Status: "removed"},
+			},
+			Excluded: []gh.ExcludedFile{
+				{
+					File:   &gh.File{Filename: "go.sum", Additions: 3},
+					Reason: "linguist-generated",
+				},
+			},
+		},
+	}
+	assert.Equal(t, "M — 40 lines in 2 files", r.Title())
+
+	got := r.Annotations()
+	require.Len(t, got, 2)
+	assert.Equal(t, "main.go", got[0].GetPath())
+	assert.Equal(t, "This file changes 40 lines (+40 / -0).", got[0].GetMessage())
+	assert.Equal(t, "go.sum", got[1].GetPath())
+	assert.Equal(t, "3 changed lines in this file are not counted: linguist-generated.", got[1].GetMessage())
+}
+
+func TestManyExcludedFiles(t *testing.T) {
+	changes := &gh.ChangedLines{Additions: 1, Files: []*gh.File{{Filename: "main.go", Additions: 1}}}
+	for i := 0; i < 5000; i++ {
+		changes.Excluded = append(changes.Excluded, gh.ExcludedFile{
+			File:   &gh.File{Filename: fmt.Sprintf("vendor/%04d.go", i), Additions: 1 + i%3},
+			Reason: "linguist-vendored",
+		})
+	}
+	r := &report.Report{Size: gh.DefaultSizer().Size(1), Tiers: gh.DefaultTiers, Changes: changes}
+
+	md := r.Markdown()
+	assert.Equal(t, 40, strings.Count(md, "| linguist-vendored |"))
+	assert.Contains(t, md, "| `vendor/0002.go` | 3 | linguist-vendored |\n")
+	assert.Contains(t, md, "\n4960 more excluded files with 9879 changed lines are not listed.\n")
+	assert.Len(t, r.Annotations(), 41)
+}